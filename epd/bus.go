@@ -0,0 +1,129 @@
+package epd
+
+import (
+	"time"
+
+	"tinygo.org/x/drivers"
+)
+
+// Type Bus is the wiring every SPI ePaper controller uses: a 4-wire SPI bus
+// with chip select and data/command pins, plus optional reset and busy pins.
+type Bus struct {
+	SPI  drivers.SPI
+	CS   Pin
+	DC   Pin
+	RST  Pin
+	BUSY Pin
+
+	// BusyLevel is the level BUSY sits at while the controller is working,
+	// the UltraChip parts pull it low and the Solomon parts drive it high
+	BusyLevel bool
+
+	// BusyTimeout bounds WaitIdle, without a BUSY pin WaitIdle just sleeps
+	// for IdleDelay
+	BusyTimeout time.Duration
+	IdleDelay   time.Duration
+
+	// ChunkSize is the number of bytes sent per SPI transfer for bulk data
+	ChunkSize int
+
+	buf [1]byte
+}
+
+// Func Configure sets up the pins
+func (b *Bus) Configure() {
+	if Connected(b.RST) {
+		Configure(b.RST, PinOutput)
+		b.RST.High()
+	}
+	if Connected(b.BUSY) {
+		Configure(b.BUSY, PinInput)
+	}
+
+	Configure(b.DC, PinOutput)
+	b.DC.Low()
+
+	Configure(b.CS, PinOutput)
+	b.CS.High()
+
+	if b.ChunkSize <= 0 {
+		b.ChunkSize = 256
+	}
+}
+
+// Func Reset pulses the reset pin if there is one
+func (b *Bus) Reset() {
+	if !Connected(b.RST) {
+		return
+	}
+	b.RST.Low()
+	time.Sleep(20 * time.Millisecond)
+	b.RST.High()
+	time.Sleep(20 * time.Millisecond)
+}
+
+// Func WaitIdle blocks until the controller releases BUSY
+func (b *Bus) WaitIdle() error {
+	if !Connected(b.BUSY) {
+		time.Sleep(b.IdleDelay)
+		return nil
+	}
+	start := time.Now()
+	for b.BUSY.Get() == b.BusyLevel {
+		if b.BusyTimeout > 0 && time.Since(start) > b.BusyTimeout {
+			return ErrBusyTimeout
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return nil
+}
+
+// Func Command sends cmd followed by its data in one transaction
+func (b *Bus) Command(cmd byte, data ...byte) error {
+	if err := b.Begin(cmd); err != nil {
+		return err
+	}
+	err := b.Data(data)
+	b.End()
+	return err
+}
+
+// Func Begin sends cmd and leaves CS asserted and DC high for a data phase,
+// which must be finished with End
+func (b *Bus) Begin(cmd byte) error {
+	b.CS.High()
+	b.DC.Low()
+	b.CS.Low()
+
+	b.buf[0] = cmd
+	if err := b.SPI.Tx(b.buf[:], nil); err != nil {
+		b.CS.High()
+		return err
+	}
+	b.DC.High()
+	return nil
+}
+
+// Func Data streams data in ChunkSize transfers
+func (b *Bus) Data(data []byte) error {
+	chunk := b.ChunkSize
+	if chunk <= 0 {
+		chunk = len(data)
+	}
+	for len(data) > 0 {
+		n := chunk
+		if n > len(data) {
+			n = len(data)
+		}
+		if err := b.SPI.Tx(data[:n], nil); err != nil {
+			return err
+		}
+		data = data[n:]
+	}
+	return nil
+}
+
+// Func End finishes a transaction started with Begin
+func (b *Bus) End() {
+	b.CS.High()
+}
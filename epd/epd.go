@@ -0,0 +1,108 @@
+// Package epd holds what the ePaper panel drivers have in common: the Device
+// interface the badge app draws through, the SPI wiring and the black/color
+// framebuffer planes.
+package epd
+
+import (
+	"errors"
+	"image"
+
+	"github.com/davidadeleon/gophercon2022Badge/framebuffer"
+	"tinygo.org/x/drivers"
+)
+
+// Panel colors, shared by every driver
+const (
+	BLACK int = iota
+	WHITE
+	INVERSE
+	RED
+	DARK
+	LIGHT
+)
+
+var (
+	ErrBusyTimeout   = errors.New("epd: timed out waiting for display")
+	ErrInvalidState  = errors.New("epd: invalid device state")
+	ErrBadDimensions = errors.New("epd: bad dimensions")
+)
+
+// Type Device is an ePaper panel driver. It's a drivers.Displayer so tinyfont
+// and friends can draw on it, plus the lifecycle every panel needs.
+type Device interface {
+	drivers.Displayer
+
+	// Init configures the pins, allocates the framebuffers and resets the panel
+	Init() error
+
+	// SetRotation rotates drawing by val quarter turns clockwise, 0 to 3
+	SetRotation(val int) error
+
+	// Fill sets the whole framebuffer to one of the panel colors, it fails
+	// with ErrInvalidState before Init
+	Fill(color int) error
+
+	// DisplayImage draws img into the framebuffers with its top left corner
+	// at x, y in the current rotation. The panel isn't refreshed.
+	DisplayImage(x, y int, img image.Image) error
+
+	// DisplayRegion refreshes only the given window of the panel, in panel
+	// (unrotated) coordinates. x and width are rounded out to whole bytes.
+	DisplayRegion(x, y, width, height int) error
+
+	// Sleep powers the panel down into its lowest power state, it needs a
+	// reset (done by the next Display) to wake up. Without a reset pin the
+	// panel is only powered off, it could never be woken from deep sleep.
+	Sleep() error
+
+	// Buffers returns the black and color planes. Monochrome panels return
+	// the same buffer twice.
+	Buffers() (black, color *framebuffer.FrameBuffer)
+}
+
+// Type AsyncDevice is a Device that can refresh the panel in the background
+// itself, coalescing frames while the panel is busy
+type AsyncDevice interface {
+	Device
+	DisplayAsync() <-chan error
+}
+
+// Func DisplayAsync refreshes d without blocking and returns a channel that
+// receives the result. Devices without DisplayAsync refresh on a goroutine of
+// their own.
+func DisplayAsync(d Device) <-chan error {
+	if a, ok := d.(AsyncDevice); ok {
+		return a.DisplayAsync()
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- d.Display()
+	}()
+	return done
+}
+
+// Func AlignRegion rounds x and width out to byte boundaries and clips the
+// region to a width x height panel
+func AlignRegion(x, y, width, height, panelWidth, panelHeight int) (int, int, int, int, error) {
+	if x < 0 {
+		width += x
+		x = 0
+	}
+	if y < 0 {
+		height += y
+		y = 0
+	}
+	if x+width > panelWidth {
+		width = panelWidth - x
+	}
+	if y+height > panelHeight {
+		height = panelHeight - y
+	}
+	if width <= 0 || height <= 0 {
+		return 0, 0, 0, 0, ErrBadDimensions
+	}
+
+	end := (x + width + 7) &^ 7
+	x &^= 7
+	return x, y, end - x, height, nil
+}
@@ -0,0 +1,152 @@
+// Package epdtest provides a fake SPI bus and pins that record the command
+// stream a driver sends, so the epd drivers can be tested without a panel.
+package epdtest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/davidadeleon/gophercon2022Badge/epd"
+)
+
+var ErrBus = errors.New("epdtest: bus fault")
+
+// Type Pin is a fake GPIO
+type Pin struct {
+	Level      bool
+	Mode       epd.PinMode
+	Configured bool
+}
+
+func (p *Pin) ConfigureMode(mode epd.PinMode) {
+	p.Mode = mode
+	p.Configured = true
+}
+
+func (p *Pin) High() {
+	p.Level = true
+}
+
+func (p *Pin) Low() {
+	p.Level = false
+}
+
+func (p *Pin) Get() bool {
+	return p.Level
+}
+
+// Type Command is a command byte and the data sent after it
+type Command struct {
+	Cmd  byte
+	Data []byte
+}
+
+func (c Command) String() string {
+	return fmt.Sprintf("0x%02x % x", c.Cmd, c.Data)
+}
+
+// Type Bus is the SPI bus and pins of a panel. It decodes what's clocked in
+// the way the controllers do, a byte sent with DC low starts a command and
+// bytes with DC high are its data, and keeps every command in Commands.
+// Bytes clocked with CS high are recorded in Violations.
+type Bus struct {
+	CS   *Pin
+	DC   *Pin
+	RST  *Pin
+	BUSY *Pin
+
+	Commands   []Command
+	Violations []error
+
+	// Fail makes every Tx return ErrBus
+	Fail bool
+}
+
+// Func NewBus returns a bus with CS idle high and BUSY at idle
+func NewBus(busyLevel bool) *Bus {
+	return &Bus{
+		CS:   &Pin{Level: true},
+		DC:   &Pin{},
+		RST:  &Pin{Level: true},
+		BUSY: &Pin{Level: !busyLevel},
+	}
+}
+
+func (b *Bus) Tx(w, r []byte) error {
+	if b.Fail {
+		return ErrBus
+	}
+	if b.CS.Level {
+		b.Violations = append(b.Violations, fmt.Errorf("epdtest: %d bytes clocked with CS high", len(w)))
+		return nil
+	}
+	for _, c := range w {
+		if !b.DC.Level {
+			b.Commands = append(b.Commands, Command{Cmd: c})
+			continue
+		}
+		if len(b.Commands) == 0 {
+			b.Violations = append(b.Violations, fmt.Errorf("epdtest: data 0x%02x without a command", c))
+			continue
+		}
+		last := &b.Commands[len(b.Commands)-1]
+		last.Data = append(last.Data, c)
+	}
+	for i := range r {
+		r[i] = 0
+	}
+	return nil
+}
+
+func (b *Bus) Transfer(c byte) (byte, error) {
+	return 0, b.Tx([]byte{c}, nil)
+}
+
+// Func Find returns every command sent with the given command byte, in order
+func (b *Bus) Find(cmd byte) []Command {
+	var found []Command
+	for _, c := range b.Commands {
+		if c.Cmd == cmd {
+			found = append(found, c)
+		}
+	}
+	return found
+}
+
+// Func Last returns the last command sent with the given command byte
+func (b *Bus) Last(cmd byte) (Command, bool) {
+	found := b.Find(cmd)
+	if len(found) == 0 {
+		return Command{}, false
+	}
+	return found[len(found)-1], true
+}
+
+// Func Clear forgets the commands and violations seen so far
+func (b *Bus) Clear() {
+	b.Commands = nil
+	b.Violations = nil
+}
+
+// Func CheckViolations reports every violation seen on the bus as an error
+func CheckViolations(t testing.TB, b *Bus) {
+	t.Helper()
+	for _, v := range b.Violations {
+		t.Error(v)
+	}
+}
+
+// Func CheckCommand fails t unless the last cmd sent carried exactly want
+func CheckCommand(t testing.TB, b *Bus, cmd byte, want ...byte) {
+	t.Helper()
+	c, ok := b.Last(cmd)
+	if !ok {
+		t.Errorf("command 0x%02x not sent", cmd)
+		return
+	}
+	if !bytes.Equal(c.Data, want) {
+		t.Errorf("command 0x%02x sent % x, want % x", cmd, c.Data, want)
+	}
+}
@@ -0,0 +1,10 @@
+module github.com/davidadeleon/gophercon2022Badge/epd
+
+go 1.19
+
+require (
+	github.com/davidadeleon/gophercon2022Badge/framebuffer v0.0.0
+	tinygo.org/x/drivers v0.22.0
+)
+
+replace github.com/davidadeleon/gophercon2022Badge/framebuffer => ../framebuffer
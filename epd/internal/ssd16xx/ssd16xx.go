@@ -0,0 +1,215 @@
+// Package ssd16xx implements what the Solomon Systech SSD1675 and SSD1680
+// controllers share: RAM windowing, the two RAM planes and update activation.
+package ssd16xx
+
+import (
+	"image/color"
+	"time"
+
+	"github.com/davidadeleon/gophercon2022Badge/epd"
+	"github.com/davidadeleon/gophercon2022Badge/framebuffer"
+)
+
+const (
+	DRIVER_OUTPUT_CONTROL   = 0x01
+	GATE_VOLTAGE            = 0x03
+	SOURCE_VOLTAGE          = 0x04
+	DEEP_SLEEP              = 0x10
+	DATA_ENTRY_MODE         = 0x11
+	SW_RESET                = 0x12
+	MASTER_ACTIVATION       = 0x20
+	DISPLAY_UPDATE_CONTROL1 = 0x21
+	DISPLAY_UPDATE_CONTROL2 = 0x22
+	WRITE_BLACK_RAM         = 0x24
+	WRITE_RED_RAM           = 0x26
+	WRITE_VCOM              = 0x2C
+	WRITE_LUT               = 0x32
+	DUMMY_LINE_PERIOD       = 0x3A
+	GATE_LINE_WIDTH         = 0x3B
+	BORDER_WAVEFORM         = 0x3C
+	SET_RAM_X_WINDOW        = 0x44
+	SET_RAM_Y_WINDOW        = 0x45
+	SET_RAM_X_COUNTER       = 0x4E
+	SET_RAM_Y_COUNTER       = 0x4F
+	ANALOG_BLOCK_CONTROL    = 0x74
+	DIGITAL_BLOCK_CONTROL   = 0x7E
+)
+
+// Type Command is one step of an init sequence
+type Command struct {
+	Cmd  byte
+	Data []byte
+}
+
+// Type Device drives an SSD16xx panel. The chip specific packages fill in
+// Sequence, the init sequence sent after every reset, and Update, the display
+// update sequence used for a full refresh.
+type Device struct {
+	epd.Bus
+	epd.Planes
+
+	Sequence []Command
+	Update   byte
+	Tricolor bool
+
+	// XOffset shifts the RAM X address on panels that don't start at the
+	// first source line
+	XOffset int
+
+	awake     bool
+	regionBuf []byte
+}
+
+// Func Setup configures the bus and allocates the planes. Black RAM stores
+// a 1 for white, red RAM a 1 for red.
+func (d *Device) Setup(width, height int) error {
+	d.Bus.BusyLevel = true
+	if d.Bus.BusyTimeout == 0 {
+		d.Bus.BusyTimeout = 20 * time.Second
+	}
+	if d.Bus.IdleDelay == 0 {
+		d.Bus.IdleDelay = 500 * time.Millisecond
+	}
+	d.Bus.Configure()
+
+	if err := d.Planes.Init(width, height, d.Tricolor); err != nil {
+		return err
+	}
+	d.Planes.BlackInverted = true
+	d.Planes.ColorInverted = false
+	d.Planes.Clear()
+	d.Bus.Reset()
+	return nil
+}
+
+// Func Wake resets the controller and runs the init sequence
+func (d *Device) Wake() error {
+	if d.awake {
+		return nil
+	}
+	d.Bus.Reset()
+	if err := d.Bus.Command(SW_RESET); err != nil {
+		return err
+	}
+	time.Sleep(20 * time.Millisecond)
+	if err := d.Bus.WaitIdle(); err != nil {
+		return err
+	}
+	for _, c := range d.Sequence {
+		if err := d.Bus.Command(c.Cmd, c.Data...); err != nil {
+			return err
+		}
+	}
+	d.awake = true
+	return nil
+}
+
+// Func Display uploads both planes and runs a full refresh
+func (d *Device) Display() error {
+	return d.refresh(0, 0, d.stride(), d.Planes.Height)
+}
+
+// Func stride is the panel width padded out to whole bytes, as stored in RAM
+func (d *Device) stride() int {
+	return (d.Planes.Width + 7) &^ 7
+}
+
+// Func DisplayRegion uploads a window of the planes and refreshes. The
+// SSD16xx parts refresh the whole panel from RAM, so only the transfer is
+// shortened.
+func (d *Device) DisplayRegion(x, y, width, height int) error {
+	x, y, width, height, err := epd.AlignRegion(x, y, width, height, d.Planes.Width, d.Planes.Height)
+	if err != nil {
+		return err
+	}
+	return d.refresh(x, y, width, height)
+}
+
+func (d *Device) refresh(x, y, width, height int) error {
+	if d.Planes.Black == nil {
+		return epd.ErrInvalidState
+	}
+	if err := d.Wake(); err != nil {
+		return err
+	}
+
+	if err := d.window(x, y, width, height); err != nil {
+		return err
+	}
+	if err := d.writeRAM(WRITE_BLACK_RAM, d.Planes.BlackBytes(), x, y, width, height); err != nil {
+		return err
+	}
+	if d.Tricolor {
+		if err := d.window(x, y, width, height); err != nil {
+			return err
+		}
+		if err := d.writeRAM(WRITE_RED_RAM, d.Planes.ColorBytes(), x, y, width, height); err != nil {
+			return err
+		}
+	}
+
+	if err := d.Bus.Command(DISPLAY_UPDATE_CONTROL2, d.Update); err != nil {
+		return err
+	}
+	if err := d.Bus.Command(MASTER_ACTIVATION); err != nil {
+		return err
+	}
+	time.Sleep(100 * time.Millisecond)
+	return d.Bus.WaitIdle()
+}
+
+func (d *Device) window(x, y, width, height int) error {
+	xs := byte((x + d.XOffset) / 8)
+	xe := byte((x+d.XOffset+width)/8 - 1)
+	ye := y + height - 1
+	if err := d.Bus.Command(DATA_ENTRY_MODE, 0x03); err != nil {
+		return err
+	}
+	if err := d.Bus.Command(SET_RAM_X_WINDOW, xs, xe); err != nil {
+		return err
+	}
+	if err := d.Bus.Command(SET_RAM_Y_WINDOW, byte(y), byte(y>>8), byte(ye), byte(ye>>8)); err != nil {
+		return err
+	}
+	if err := d.Bus.Command(SET_RAM_X_COUNTER, xs); err != nil {
+		return err
+	}
+	return d.Bus.Command(SET_RAM_Y_COUNTER, byte(y), byte(y>>8))
+}
+
+func (d *Device) writeRAM(cmd byte, plane []byte, x, y, width, height int) error {
+	data := plane
+	if x != 0 || y != 0 || width != d.stride() || height != d.Planes.Height {
+		d.regionBuf = d.Planes.Region(plane, x, y, width, height, d.regionBuf)
+		data = d.regionBuf
+	}
+	if err := d.Bus.Begin(cmd); err != nil {
+		return err
+	}
+	err := d.Bus.Data(data)
+	d.Bus.End()
+	return err
+}
+
+// Func Sleep puts the controller in deep sleep mode 1, RAM is retained but
+// a reset is needed to talk to it again. Without a reset pin it's left as the
+// last update left it, with the analog supplies off.
+func (d *Device) Sleep() error {
+	if !d.awake || !epd.Connected(d.Bus.RST) {
+		return nil
+	}
+	d.awake = false
+	return d.Bus.Command(DEEP_SLEEP, 0x01)
+}
+
+func (d *Device) Buffers() (black, color *framebuffer.FrameBuffer) {
+	return d.Planes.Black, d.Planes.Color
+}
+
+func (d *Device) Size() (int16, int16) {
+	return d.Planes.Size()
+}
+
+func (d *Device) SetPixel(x, y int16, c color.RGBA) {
+	d.Planes.SetPixel(x, y, c)
+}
@@ -0,0 +1,40 @@
+package epd
+
+// Type PinMode is how a driver sets a pin up
+type PinMode uint8
+
+const (
+	PinOutput PinMode = iota
+	PinInput
+)
+
+// Type Pin is the part of machine.Pin the drivers use, so host-side fakes can
+// stand in for real pins. Set pins up with Configure.
+type Pin interface {
+	High()
+	Low()
+	Get() bool
+}
+
+// Type ModePin is a Pin that sets itself up from a PinMode, fakes implement
+// it to see how the driver configured them
+type ModePin interface {
+	Pin
+	ConfigureMode(mode PinMode)
+}
+
+// Func Configure sets p up as an output or input. A machine.Pin is configured
+// directly when built with TinyGo, other pins only if they're a ModePin.
+func Configure(p Pin, mode PinMode) {
+	if m, ok := p.(ModePin); ok {
+		m.ConfigureMode(mode)
+		return
+	}
+	configureMachine(p, mode)
+}
+
+// Func Connected reports whether an optional pin is wired up, nil and
+// machine.NoPin both mean it isn't
+func Connected(p Pin) bool {
+	return p != nil && !isNoPin(p)
+}
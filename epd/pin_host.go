@@ -0,0 +1,11 @@
+//go:build !tinygo
+
+package epd
+
+// Without TinyGo there is no machine package, every pin is a fake
+
+func configureMachine(p Pin, mode PinMode) {}
+
+func isNoPin(p Pin) bool {
+	return false
+}
@@ -0,0 +1,22 @@
+//go:build tinygo
+
+package epd
+
+import "machine"
+
+func configureMachine(p Pin, mode PinMode) {
+	pin, ok := p.(machine.Pin)
+	if !ok {
+		return
+	}
+	config := machine.PinConfig{Mode: machine.PinOutput}
+	if mode == PinInput {
+		config.Mode = machine.PinInput
+	}
+	pin.Configure(config)
+}
+
+func isNoPin(p Pin) bool {
+	pin, ok := p.(machine.Pin)
+	return ok && pin == machine.NoPin
+}
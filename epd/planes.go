@@ -0,0 +1,188 @@
+package epd
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/davidadeleon/gophercon2022Badge/framebuffer"
+)
+
+// Type Planes holds the black and color framebuffers of a panel and the
+// drawing functions that don't depend on the controller. Inverted planes store
+// a 0 bit for an inked pixel.
+type Planes struct {
+	Width  int
+	Height int
+
+	Black         *framebuffer.FrameBuffer
+	Color         *framebuffer.FrameBuffer
+	BlackInverted bool
+	ColorInverted bool
+
+	blackBuf []byte
+	colorBuf []byte
+}
+
+// Func Init allocates the planes for a width x height panel. Rows are padded
+// to whole bytes. Monochrome panels share a single plane.
+func (p *Planes) Init(width, height int, tricolor bool) error {
+	if width <= 0 || height <= 0 {
+		return ErrBadDimensions
+	}
+	p.Width = width
+	p.Height = height
+
+	stride := (width + 7) &^ 7
+	size := stride * height / 8
+
+	p.blackBuf = make([]byte, size)
+	p.Black = &framebuffer.FrameBuffer{
+		Buf:    &p.blackBuf,
+		Width:  width,
+		Height: height,
+		Stride: stride,
+	}
+
+	if !tricolor {
+		p.Color = p.Black
+		return nil
+	}
+	p.colorBuf = make([]byte, size)
+	p.Color = &framebuffer.FrameBuffer{
+		Buf:    &p.colorBuf,
+		Width:  width,
+		Height: height,
+		Stride: stride,
+	}
+	return nil
+}
+
+// Func Tricolor reports whether there is a separate color plane
+func (p *Planes) Tricolor() bool {
+	return p.Color != p.Black
+}
+
+// Func BlackBytes returns the raw black plane
+func (p *Planes) BlackBytes() []byte {
+	return p.blackBuf
+}
+
+// Func ColorBytes returns the raw color plane, nil on monochrome panels
+func (p *Planes) ColorBytes() []byte {
+	return p.colorBuf
+}
+
+// Func Region copies the bytes covering a byte aligned window of a plane into
+// dst, row by row, and returns the filled part of dst
+func (p *Planes) Region(plane []byte, x, y, width, height int, dst []byte) []byte {
+	stride := ((p.Width + 7) &^ 7) / 8
+	rowBytes := width / 8
+	dst = dst[:0]
+	for row := y; row < y+height; row++ {
+		start := row*stride + x/8
+		dst = append(dst, plane[start:start+rowBytes]...)
+	}
+	return dst
+}
+
+// Func SetRotation rotates both planes
+func (p *Planes) SetRotation(val int) error {
+	if err := p.Black.SetRotation(val); err != nil {
+		return err
+	}
+	return p.Color.SetRotation(val)
+}
+
+// Func Size returns the size of the planes as currently rotated
+func (p *Planes) Size() (int16, int16) {
+	if p.Black != nil && p.Black.Rotation&1 == 1 {
+		return int16(p.Height), int16(p.Width)
+	}
+	return int16(p.Width), int16(p.Height)
+}
+
+func (p *Planes) Clear() {
+	p.Fill(WHITE)
+}
+
+func (p *Planes) Fill(color int) error {
+	if p.Black == nil {
+		return ErrInvalidState
+	}
+	var black, red byte
+	if (color == BLACK) != p.BlackInverted {
+		black = 0xFF
+	}
+	if (color == RED) != p.ColorInverted {
+		red = 0xFF
+	}
+	p.Black.Fill(black)
+	if p.Tricolor() {
+		p.Color.Fill(red)
+	}
+	return nil
+}
+
+func (p *Planes) Pixel(x, y int, color int) {
+	if !p.Tricolor() {
+		if (color == BLACK) != p.BlackInverted {
+			p.Black.Pixel(x, y, 1)
+		} else {
+			p.Black.Pixel(x, y, 0)
+		}
+		return
+	}
+
+	if (color == BLACK) != p.BlackInverted {
+		p.Black.Pixel(x, y, 1)
+	} else {
+		p.Black.Pixel(x, y, 0)
+	}
+
+	if (color == RED) != p.ColorInverted {
+		p.Color.Pixel(x, y, 1)
+	} else {
+		p.Color.Pixel(x, y, 0)
+	}
+}
+
+// Func SetPixel draws c, mapped onto the nearest of white, black and red
+func (p *Planes) SetPixel(x, y int16, c color.RGBA) {
+	p.Pixel(int(x), int(y), Nearest(c))
+}
+
+// Func DisplayImage draws img with its top left corner at x, y in the current
+// rotation, mapping colors with Nearest. Transparent pixels are skipped.
+func (p *Planes) DisplayImage(x, y int, img image.Image) error {
+	if p.Black == nil {
+		return ErrInvalidState
+	}
+	bounds := img.Bounds()
+	width, height := p.Size()
+	if x < 0 || y < 0 || x+bounds.Dx() > int(width) || y+bounds.Dy() > int(height) {
+		return ErrBadDimensions
+	}
+	for iy := bounds.Min.Y; iy < bounds.Max.Y; iy++ {
+		for ix := bounds.Min.X; ix < bounds.Max.X; ix++ {
+			c := color.RGBAModel.Convert(img.At(ix, iy)).(color.RGBA)
+			if c.A == 0 {
+				continue
+			}
+			p.Pixel(x+ix-bounds.Min.X, y+iy-bounds.Min.Y, Nearest(c))
+		}
+	}
+	return nil
+}
+
+// Func Nearest maps c onto WHITE, BLACK or RED
+func Nearest(c color.RGBA) int {
+	r, g, b := int(c.R), int(c.G), int(c.B)
+	switch {
+	case r > 0x80 && g < 0x80 && b < 0x80:
+		return RED
+	case r+g+b < 3*0x80:
+		return BLACK
+	default:
+		return WHITE
+	}
+}
@@ -0,0 +1,52 @@
+// Package ssd1675 drives panels on the Solomon Systech SSD1675, such as the
+// older 2.13" tri-color and monochrome FeatherWings.
+package ssd1675
+
+import (
+	"github.com/davidadeleon/gophercon2022Badge/epd"
+	"github.com/davidadeleon/gophercon2022Badge/epd/internal/ssd16xx"
+	"tinygo.org/x/drivers"
+)
+
+var _ epd.Device = (*Device)(nil)
+
+type Device struct {
+	ssd16xx.Device
+}
+
+// Func New returns a driver for a width x height SSD1675 panel, set tricolor
+// for the black/white/red panels
+func New(width, height int, tricolor bool, bus drivers.SPI, csPin, dcPin, rstPin, busyPin epd.Pin) *Device {
+	d := &Device{}
+	d.Bus = epd.Bus{
+		SPI:  bus,
+		CS:   csPin,
+		DC:   dcPin,
+		RST:  rstPin,
+		BUSY: busyPin,
+	}
+	d.Planes.Width = width
+	d.Planes.Height = height
+	d.Tricolor = tricolor
+	// Load the temperature compensated waveform from OTP and refresh
+	d.Update = 0xC7
+
+	gates := height - 1
+	d.Sequence = []ssd16xx.Command{
+		{Cmd: ssd16xx.ANALOG_BLOCK_CONTROL, Data: []byte{0x54}},
+		{Cmd: ssd16xx.DIGITAL_BLOCK_CONTROL, Data: []byte{0x3B}},
+		{Cmd: ssd16xx.DRIVER_OUTPUT_CONTROL, Data: []byte{byte(gates), byte(gates >> 8), 0x00}},
+		{Cmd: ssd16xx.DUMMY_LINE_PERIOD, Data: []byte{0x07}},
+		{Cmd: ssd16xx.GATE_LINE_WIDTH, Data: []byte{0x08}},
+		{Cmd: ssd16xx.BORDER_WAVEFORM, Data: []byte{0x03}},
+		{Cmd: ssd16xx.DATA_ENTRY_MODE, Data: []byte{0x03}},
+		{Cmd: ssd16xx.WRITE_VCOM, Data: []byte{0x50}},
+		{Cmd: ssd16xx.GATE_VOLTAGE, Data: []byte{0x15}},
+		{Cmd: ssd16xx.SOURCE_VOLTAGE, Data: []byte{0x41, 0xA8, 0x32}},
+	}
+	return d
+}
+
+func (d *Device) Init() error {
+	return d.Setup(d.Planes.Width, d.Planes.Height)
+}
@@ -0,0 +1,52 @@
+package ssd1675_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/davidadeleon/gophercon2022Badge/epd"
+	"github.com/davidadeleon/gophercon2022Badge/epd/epdtest"
+	"github.com/davidadeleon/gophercon2022Badge/epd/internal/ssd16xx"
+	"github.com/davidadeleon/gophercon2022Badge/epd/ssd1675"
+)
+
+func TestDisplay(t *testing.T) {
+	bus := epdtest.NewBus(true)
+	d := ssd1675.New(122, 250, false, bus, bus.CS, bus.DC, bus.RST, bus.BUSY)
+	if err := d.Display(); !errors.Is(err, epd.ErrInvalidState) {
+		t.Errorf("Display before Init: err = %v", err)
+	}
+	if err := d.Init(); err != nil {
+		t.Fatal(err)
+	}
+	d.Fill(epd.BLACK)
+	if err := d.Display(); err != nil {
+		t.Fatal(err)
+	}
+	epdtest.CheckViolations(t, bus)
+
+	// The gate count is the panel height less one
+	if c, ok := bus.Last(ssd16xx.DRIVER_OUTPUT_CONTROL); !ok || c.Data[0] != 249 || c.Data[1] != 0 {
+		t.Errorf("driver output control %v", c)
+	}
+	black, _ := bus.Last(ssd16xx.WRITE_BLACK_RAM)
+	if len(black.Data) != 16*250 {
+		t.Fatalf("black RAM write of %d bytes, want %d", len(black.Data), 16*250)
+	}
+	for i, b := range black.Data {
+		if b != 0x00 {
+			t.Fatalf("black RAM byte %d = %02x, want 00", i, b)
+		}
+	}
+	if len(bus.Find(ssd16xx.WRITE_RED_RAM)) != 0 {
+		t.Error("red RAM written on a monochrome panel")
+	}
+	epdtest.CheckCommand(t, bus, ssd16xx.DISPLAY_UPDATE_CONTROL2, 0xC7)
+
+	if err := d.Sleep(); err != nil {
+		t.Fatal(err)
+	}
+	if len(bus.Find(ssd16xx.DEEP_SLEEP)) != 1 {
+		t.Error("deep sleep not entered")
+	}
+}
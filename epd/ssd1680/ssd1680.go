@@ -0,0 +1,51 @@
+// Package ssd1680 drives panels on the Solomon Systech SSD1680, such as the
+// 2.13" and 2.9" tri-color FeatherWings.
+package ssd1680
+
+import (
+	"github.com/davidadeleon/gophercon2022Badge/epd"
+	"github.com/davidadeleon/gophercon2022Badge/epd/internal/ssd16xx"
+	"tinygo.org/x/drivers"
+)
+
+var _ epd.Device = (*Device)(nil)
+
+type Device struct {
+	ssd16xx.Device
+}
+
+// Func New returns a driver for a width x height SSD1680 panel, set tricolor
+// for the black/white/red panels
+func New(width, height int, tricolor bool, bus drivers.SPI, csPin, dcPin, rstPin, busyPin epd.Pin) *Device {
+	d := &Device{}
+	d.Bus = epd.Bus{
+		SPI:  bus,
+		CS:   csPin,
+		DC:   dcPin,
+		RST:  rstPin,
+		BUSY: busyPin,
+	}
+	d.Planes.Width = width
+	d.Planes.Height = height
+	d.Tricolor = tricolor
+	d.Update = 0xF4
+
+	gates := height - 1
+	d.Sequence = []ssd16xx.Command{
+		{Cmd: ssd16xx.DATA_ENTRY_MODE, Data: []byte{0x03}},
+		{Cmd: ssd16xx.BORDER_WAVEFORM, Data: []byte{0x05}},
+		{Cmd: ssd16xx.WRITE_VCOM, Data: []byte{0x36}},
+		{Cmd: ssd16xx.GATE_VOLTAGE, Data: []byte{0x17}},
+		{Cmd: ssd16xx.SOURCE_VOLTAGE, Data: []byte{0x41, 0x00, 0x32}},
+		{Cmd: ssd16xx.DRIVER_OUTPUT_CONTROL, Data: []byte{byte(gates), byte(gates >> 8), 0x00}},
+	}
+	// The 2.13" glass starts one byte in on the source lines
+	if width < 128 {
+		d.XOffset = 8
+	}
+	return d
+}
+
+func (d *Device) Init() error {
+	return d.Setup(d.Planes.Width, d.Planes.Height)
+}
@@ -0,0 +1,188 @@
+package ssd1680_test
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/davidadeleon/gophercon2022Badge/epd"
+	"github.com/davidadeleon/gophercon2022Badge/epd/epdtest"
+	"github.com/davidadeleon/gophercon2022Badge/epd/internal/ssd16xx"
+	"github.com/davidadeleon/gophercon2022Badge/epd/ssd1680"
+)
+
+func newPanel(t *testing.T, width, height int) (*epdtest.Bus, *ssd1680.Device) {
+	t.Helper()
+	bus := epdtest.NewBus(true)
+	d := ssd1680.New(width, height, true, bus, bus.CS, bus.DC, bus.RST, bus.BUSY)
+	if err := d.Init(); err != nil {
+		t.Fatal(err)
+	}
+	return bus, d
+}
+
+func TestDisplay(t *testing.T) {
+	bus, d := newPanel(t, 128, 296)
+	d.Fill(epd.WHITE)
+	d.Pixel(0, 0, epd.BLACK)
+	d.Pixel(8, 1, epd.RED)
+	if err := d.Display(); err != nil {
+		t.Fatal(err)
+	}
+	epdtest.CheckViolations(t, bus)
+
+	if bus.Commands[0].Cmd != ssd16xx.SW_RESET {
+		t.Errorf("first command %v, want a software reset", bus.Commands[0])
+	}
+	for _, c := range d.Sequence {
+		epdtest.CheckCommand(t, bus, c.Cmd, c.Data...)
+	}
+	epdtest.CheckCommand(t, bus, ssd16xx.SET_RAM_X_WINDOW, 0, 15)
+	epdtest.CheckCommand(t, bus, ssd16xx.SET_RAM_Y_WINDOW, 0, 0, 0x27, 1)
+
+	// Black RAM holds a 1 for white, red RAM a 1 for red
+	blackRAM, _ := bus.Last(ssd16xx.WRITE_BLACK_RAM)
+	redRAM, _ := bus.Last(ssd16xx.WRITE_RED_RAM)
+	if len(blackRAM.Data) != 16*296 || len(redRAM.Data) != 16*296 {
+		t.Fatalf("RAM writes of %d and %d bytes", len(blackRAM.Data), len(redRAM.Data))
+	}
+	if blackRAM.Data[0] != 0x7F || blackRAM.Data[1] != 0xFF {
+		t.Errorf("black RAM starts % x", blackRAM.Data[:2])
+	}
+	if redRAM.Data[16+1] != 0x80 || redRAM.Data[0] != 0x00 {
+		t.Errorf("red RAM row 1 starts % x", redRAM.Data[16:18])
+	}
+	epdtest.CheckCommand(t, bus, ssd16xx.DISPLAY_UPDATE_CONTROL2, 0xF4)
+	epdtest.CheckCommand(t, bus, ssd16xx.MASTER_ACTIVATION)
+
+	// Still awake, the second refresh skips the init sequence
+	bus.Clear()
+	if err := d.Display(); err != nil {
+		t.Fatal(err)
+	}
+	if len(bus.Find(ssd16xx.SW_RESET)) != 0 {
+		t.Error("init sequence sent again while awake")
+	}
+}
+
+// The SSD1680 has no DisplayAsync of its own, epd.DisplayAsync falls back to
+// a goroutine running Display
+func TestDisplayAsync(t *testing.T) {
+	bus, d := newPanel(t, 128, 296)
+	if _, ok := epd.Device(d).(epd.AsyncDevice); ok {
+		t.Fatal("ssd1680 is an AsyncDevice")
+	}
+	if err := <-epd.DisplayAsync(d); err != nil {
+		t.Fatal(err)
+	}
+	epdtest.CheckViolations(t, bus)
+	epdtest.CheckCommand(t, bus, ssd16xx.MASTER_ACTIVATION)
+}
+
+func TestDisplayRegion(t *testing.T) {
+	bus, d := newPanel(t, 128, 296)
+	bus.Clear()
+	// Rounded out to bytes 1 and 2 in X
+	if err := d.DisplayRegion(10, 20, 10, 3); err != nil {
+		t.Fatal(err)
+	}
+	epdtest.CheckViolations(t, bus)
+	epdtest.CheckCommand(t, bus, ssd16xx.SET_RAM_X_WINDOW, 1, 2)
+	epdtest.CheckCommand(t, bus, ssd16xx.SET_RAM_Y_WINDOW, 20, 0, 22, 0)
+	if c, _ := bus.Last(ssd16xx.WRITE_BLACK_RAM); len(c.Data) != 2*3 {
+		t.Errorf("region write of %d bytes, want 6", len(c.Data))
+	}
+
+	if err := d.DisplayRegion(200, 0, 8, 8); !errors.Is(err, epd.ErrBadDimensions) {
+		t.Errorf("region off the panel: err = %v", err)
+	}
+}
+
+func TestXOffset(t *testing.T) {
+	// The 2.13" glass starts one byte in
+	bus, d := newPanel(t, 122, 250)
+	if err := d.Display(); err != nil {
+		t.Fatal(err)
+	}
+	epdtest.CheckCommand(t, bus, ssd16xx.SET_RAM_X_WINDOW, 1, 16)
+}
+
+func TestDisplayImage(t *testing.T) {
+	bus, d := newPanel(t, 128, 296)
+	var dev epd.Device = d
+	dev.Fill(epd.WHITE)
+
+	img := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	img.Set(0, 0, color.RGBA{0x00, 0x00, 0x00, 0xFF})
+	img.Set(1, 0, color.RGBA{0xFF, 0x00, 0x00, 0xFF})
+	if err := dev.DisplayImage(8, 0, img); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.DisplayImage(127, 0, img); !errors.Is(err, epd.ErrBadDimensions) {
+		t.Errorf("image off the edge: err = %v", err)
+	}
+	if err := dev.Display(); err != nil {
+		t.Fatal(err)
+	}
+	blackRAM, _ := bus.Last(ssd16xx.WRITE_BLACK_RAM)
+	redRAM, _ := bus.Last(ssd16xx.WRITE_RED_RAM)
+	if blackRAM.Data[1] != 0x7F || redRAM.Data[1] != 0x40 {
+		t.Errorf("black RAM byte 1 = %02x, red RAM byte 1 = %02x", blackRAM.Data[1], redRAM.Data[1])
+	}
+}
+
+func TestSleep(t *testing.T) {
+	bus, d := newPanel(t, 128, 296)
+	if err := d.Display(); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Sleep(); err != nil {
+		t.Fatal(err)
+	}
+	epdtest.CheckCommand(t, bus, ssd16xx.DEEP_SLEEP, 0x01)
+
+	// Woken by a reset on the next refresh
+	bus.Clear()
+	if err := d.Display(); err != nil {
+		t.Fatal(err)
+	}
+	if len(bus.Find(ssd16xx.SW_RESET)) != 1 {
+		t.Error("not woken up after deep sleep")
+	}
+}
+
+func TestSleepWithoutReset(t *testing.T) {
+	bus := epdtest.NewBus(true)
+	d := ssd1680.New(128, 296, true, bus, bus.CS, bus.DC, nil, bus.BUSY)
+	if err := d.Init(); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Display(); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Sleep(); err != nil {
+		t.Fatal(err)
+	}
+	if len(bus.Find(ssd16xx.DEEP_SLEEP)) != 0 {
+		t.Error("deep sleep entered with no reset pin to leave it")
+	}
+}
+
+func TestErrors(t *testing.T) {
+	bus := epdtest.NewBus(true)
+	d := ssd1680.New(128, 296, true, bus, bus.CS, bus.DC, bus.RST, bus.BUSY)
+	if err := d.Display(); !errors.Is(err, epd.ErrInvalidState) {
+		t.Errorf("Display before Init: err = %v", err)
+	}
+	if err := d.Fill(epd.WHITE); !errors.Is(err, epd.ErrInvalidState) {
+		t.Errorf("Fill before Init: err = %v", err)
+	}
+	if err := d.Init(); err != nil {
+		t.Fatal(err)
+	}
+	bus.Fail = true
+	if err := d.Display(); !errors.Is(err, epdtest.ErrBus) {
+		t.Errorf("Display with a failing bus: err = %v", err)
+	}
+}
@@ -0,0 +1,236 @@
+// Package uc8151 drives panels on the UltraChip UC8151D, such as the 2.9"
+// flexible monochrome and tri-color FeatherWings.
+package uc8151
+
+import (
+	"image/color"
+	"time"
+
+	"github.com/davidadeleon/gophercon2022Badge/epd"
+	"github.com/davidadeleon/gophercon2022Badge/framebuffer"
+	"tinygo.org/x/drivers"
+)
+
+const (
+	PANEL_SETTING          = 0x00
+	POWER_SETTING          = 0x01
+	POWER_OFF              = 0x02
+	POWER_ON               = 0x04
+	BOOSTER_SOFT_START     = 0x06
+	DEEP_SLEEP             = 0x07
+	DTM1                   = 0x10
+	DISPLAY_REFRESH        = 0x12
+	DTM2                   = 0x13
+	PLL                    = 0x30
+	CDI                    = 0x50
+	RESOLUTION             = 0x61
+	VCM_DC_SETTING         = 0x82
+	PARTIAL_WINDOW         = 0x90
+	PARTIAL_IN             = 0x91
+	PARTIAL_OUT            = 0x92
+	DEEP_SLEEP_CHECK_CODE  = 0xA5
+	PANEL_SETTING_TRICOLOR = 0x0F
+	PANEL_SETTING_MONO     = 0x1F
+)
+
+var _ epd.Device = (*Device)(nil)
+
+type Device struct {
+	epd.Bus
+	epd.Planes
+
+	tricolor  bool
+	awake     bool
+	regionBuf []byte
+}
+
+// Func New returns a driver for a width x height UC8151D panel, set tricolor
+// for the black/white/red panels
+func New(width, height int, tricolor bool, bus drivers.SPI, csPin, dcPin, rstPin, busyPin epd.Pin) *Device {
+	d := &Device{tricolor: tricolor}
+	d.Bus = epd.Bus{
+		SPI:  bus,
+		CS:   csPin,
+		DC:   dcPin,
+		RST:  rstPin,
+		BUSY: busyPin,
+		// BUSY is pulled low while the controller works
+		BusyLevel:   false,
+		BusyTimeout: 30 * time.Second,
+		IdleDelay:   500 * time.Millisecond,
+	}
+	d.Planes.Width = width
+	d.Planes.Height = height
+	return d
+}
+
+func (d *Device) Init() error {
+	d.Bus.Configure()
+	if err := d.Planes.Init(d.Planes.Width, d.Planes.Height, d.tricolor); err != nil {
+		return err
+	}
+	// Both RAMs store a 0 for an inked pixel
+	d.Planes.BlackInverted = true
+	d.Planes.ColorInverted = true
+	d.Planes.Clear()
+	d.Bus.Reset()
+	return nil
+}
+
+func (d *Device) wake() error {
+	if d.awake {
+		return nil
+	}
+	d.Bus.Reset()
+	if err := d.Bus.WaitIdle(); err != nil {
+		return err
+	}
+
+	panel := byte(PANEL_SETTING_MONO)
+	if d.tricolor {
+		panel = PANEL_SETTING_TRICOLOR
+	}
+	if err := d.Bus.Command(POWER_SETTING, 0x03, 0x00, 0x2B, 0x2B, 0x09); err != nil {
+		return err
+	}
+	if err := d.Bus.Command(BOOSTER_SOFT_START, 0x17, 0x17, 0x17); err != nil {
+		return err
+	}
+	if err := d.Bus.Command(POWER_ON); err != nil {
+		return err
+	}
+	time.Sleep(200 * time.Millisecond)
+	if err := d.Bus.WaitIdle(); err != nil {
+		return err
+	}
+	if err := d.Bus.Command(PANEL_SETTING, panel); err != nil {
+		return err
+	}
+	if err := d.Bus.Command(PLL, 0x29); err != nil {
+		return err
+	}
+	if err := d.Bus.Command(RESOLUTION, byte(d.stride()), byte(d.Planes.Height>>8), byte(d.Planes.Height)); err != nil {
+		return err
+	}
+	if err := d.Bus.Command(VCM_DC_SETTING, 0x0A); err != nil {
+		return err
+	}
+	if err := d.Bus.Command(CDI, 0x97); err != nil {
+		return err
+	}
+	d.awake = true
+	return nil
+}
+
+func (d *Device) stride() int {
+	return (d.Planes.Width + 7) &^ 7
+}
+
+// Func Display uploads both planes and runs a full refresh
+func (d *Device) Display() error {
+	if d.Planes.Black == nil {
+		return epd.ErrInvalidState
+	}
+	if err := d.wake(); err != nil {
+		return err
+	}
+	if err := d.write(DTM1, d.Planes.BlackBytes()); err != nil {
+		return err
+	}
+	if d.tricolor {
+		if err := d.write(DTM2, d.Planes.ColorBytes()); err != nil {
+			return err
+		}
+	}
+	return d.update()
+}
+
+// Func DisplayRegion refreshes a window of the panel using the controller's
+// partial mode
+func (d *Device) DisplayRegion(x, y, width, height int) error {
+	x, y, width, height, err := epd.AlignRegion(x, y, width, height, d.Planes.Width, d.Planes.Height)
+	if err != nil {
+		return err
+	}
+	if d.Planes.Black == nil {
+		return epd.ErrInvalidState
+	}
+	if err := d.wake(); err != nil {
+		return err
+	}
+
+	xe := x + width - 1
+	ye := y + height - 1
+	if err := d.Bus.Command(PARTIAL_IN); err != nil {
+		return err
+	}
+	if err := d.Bus.Command(PARTIAL_WINDOW, byte(x), byte(xe)|0x07, byte(y>>8), byte(y), byte(ye>>8), byte(ye), 0x01); err != nil {
+		return err
+	}
+
+	d.regionBuf = d.Planes.Region(d.Planes.BlackBytes(), x, y, width, height, d.regionBuf)
+	if err := d.write(DTM1, d.regionBuf); err != nil {
+		return err
+	}
+	if d.tricolor {
+		d.regionBuf = d.Planes.Region(d.Planes.ColorBytes(), x, y, width, height, d.regionBuf)
+		if err := d.write(DTM2, d.regionBuf); err != nil {
+			return err
+		}
+	}
+	if err := d.update(); err != nil {
+		return err
+	}
+	return d.Bus.Command(PARTIAL_OUT)
+}
+
+func (d *Device) write(cmd byte, data []byte) error {
+	if err := d.Bus.Begin(cmd); err != nil {
+		return err
+	}
+	err := d.Bus.Data(data)
+	d.Bus.End()
+	return err
+}
+
+func (d *Device) update() error {
+	if err := d.Bus.Command(DISPLAY_REFRESH); err != nil {
+		return err
+	}
+	time.Sleep(100 * time.Millisecond)
+	return d.Bus.WaitIdle()
+}
+
+// Func Sleep floats the border, powers off and enters deep sleep, unless
+// there's no reset pin to wake it with
+func (d *Device) Sleep() error {
+	if !d.awake {
+		return nil
+	}
+	d.awake = false
+	if err := d.Bus.Command(CDI, 0xF7); err != nil {
+		return err
+	}
+	if err := d.Bus.Command(POWER_OFF); err != nil {
+		return err
+	}
+	if err := d.Bus.WaitIdle(); err != nil {
+		return err
+	}
+	if !epd.Connected(d.Bus.RST) {
+		return nil
+	}
+	return d.Bus.Command(DEEP_SLEEP, DEEP_SLEEP_CHECK_CODE)
+}
+
+func (d *Device) Buffers() (black, color *framebuffer.FrameBuffer) {
+	return d.Planes.Black, d.Planes.Color
+}
+
+func (d *Device) Size() (int16, int16) {
+	return d.Planes.Size()
+}
+
+func (d *Device) SetPixel(x, y int16, c color.RGBA) {
+	d.Planes.SetPixel(x, y, c)
+}
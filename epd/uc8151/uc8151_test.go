@@ -0,0 +1,139 @@
+package uc8151_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/davidadeleon/gophercon2022Badge/epd"
+	"github.com/davidadeleon/gophercon2022Badge/epd/epdtest"
+	"github.com/davidadeleon/gophercon2022Badge/epd/uc8151"
+)
+
+func newPanel(t *testing.T, tricolor bool) (*epdtest.Bus, *uc8151.Device) {
+	t.Helper()
+	// BUSY is active low on the UC8151D
+	bus := epdtest.NewBus(false)
+	d := uc8151.New(128, 296, tricolor, bus, bus.CS, bus.DC, bus.RST, bus.BUSY)
+	if err := d.Init(); err != nil {
+		t.Fatal(err)
+	}
+	return bus, d
+}
+
+func TestDisplay(t *testing.T) {
+	bus, d := newPanel(t, true)
+	d.Fill(epd.WHITE)
+	d.Pixel(0, 0, epd.BLACK)
+	d.Pixel(8, 1, epd.RED)
+	if err := d.Display(); err != nil {
+		t.Fatal(err)
+	}
+	epdtest.CheckViolations(t, bus)
+
+	epdtest.CheckCommand(t, bus, uc8151.PANEL_SETTING, uc8151.PANEL_SETTING_TRICOLOR)
+	epdtest.CheckCommand(t, bus, uc8151.RESOLUTION, 128, 1, 0x28)
+	if len(bus.Find(uc8151.POWER_ON)) != 1 {
+		t.Error("not powered on")
+	}
+
+	// Both RAMs store a 0 for an inked pixel
+	dtm1, _ := bus.Last(uc8151.DTM1)
+	dtm2, _ := bus.Last(uc8151.DTM2)
+	if len(dtm1.Data) != 16*296 || len(dtm2.Data) != 16*296 {
+		t.Fatalf("RAM writes of %d and %d bytes", len(dtm1.Data), len(dtm2.Data))
+	}
+	if dtm1.Data[0] != 0x7F || dtm1.Data[1] != 0xFF {
+		t.Errorf("DTM1 starts % x", dtm1.Data[:2])
+	}
+	if dtm2.Data[16+1] != 0x7F || dtm2.Data[0] != 0xFF {
+		t.Errorf("DTM2 row 1 starts % x", dtm2.Data[16:18])
+	}
+	if len(bus.Find(uc8151.DISPLAY_REFRESH)) != 1 {
+		t.Error("no refresh")
+	}
+}
+
+func TestMonochrome(t *testing.T) {
+	bus, d := newPanel(t, false)
+	if err := d.Display(); err != nil {
+		t.Fatal(err)
+	}
+	epdtest.CheckViolations(t, bus)
+	epdtest.CheckCommand(t, bus, uc8151.PANEL_SETTING, uc8151.PANEL_SETTING_MONO)
+	if len(bus.Find(uc8151.DTM2)) != 0 {
+		t.Error("DTM2 written on a monochrome panel")
+	}
+}
+
+func TestDisplayRegion(t *testing.T) {
+	bus, d := newPanel(t, true)
+	if err := d.DisplayRegion(10, 20, 10, 3); err != nil {
+		t.Fatal(err)
+	}
+	epdtest.CheckViolations(t, bus)
+	// Rounded out to x 8..23
+	epdtest.CheckCommand(t, bus, uc8151.PARTIAL_WINDOW, 8, 23, 0, 20, 0, 22, 0x01)
+	if c, _ := bus.Last(uc8151.DTM1); len(c.Data) != 2*3 {
+		t.Errorf("region write of %d bytes, want 6", len(c.Data))
+	}
+	if len(bus.Find(uc8151.PARTIAL_IN)) != 1 || len(bus.Find(uc8151.PARTIAL_OUT)) != 1 {
+		t.Error("partial mode not entered and left")
+	}
+
+	if err := d.DisplayRegion(0, 296, 8, 8); !errors.Is(err, epd.ErrBadDimensions) {
+		t.Errorf("region off the panel: err = %v", err)
+	}
+}
+
+func TestSleep(t *testing.T) {
+	bus, d := newPanel(t, true)
+	if err := d.Display(); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Sleep(); err != nil {
+		t.Fatal(err)
+	}
+	epdtest.CheckCommand(t, bus, uc8151.CDI, 0xF7)
+	if len(bus.Find(uc8151.POWER_OFF)) != 1 {
+		t.Error("not powered off")
+	}
+	epdtest.CheckCommand(t, bus, uc8151.DEEP_SLEEP, uc8151.DEEP_SLEEP_CHECK_CODE)
+}
+
+func TestSleepWithoutReset(t *testing.T) {
+	bus := epdtest.NewBus(false)
+	d := uc8151.New(128, 296, true, bus, bus.CS, bus.DC, nil, bus.BUSY)
+	if err := d.Init(); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Display(); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Sleep(); err != nil {
+		t.Fatal(err)
+	}
+	if len(bus.Find(uc8151.POWER_OFF)) != 1 {
+		t.Error("not powered off")
+	}
+	if len(bus.Find(uc8151.DEEP_SLEEP)) != 0 {
+		t.Error("deep sleep entered with no reset pin to leave it")
+	}
+}
+
+func TestErrors(t *testing.T) {
+	bus := epdtest.NewBus(false)
+	d := uc8151.New(128, 296, true, bus, bus.CS, bus.DC, bus.RST, bus.BUSY)
+	if err := d.Display(); !errors.Is(err, epd.ErrInvalidState) {
+		t.Errorf("Display before Init: err = %v", err)
+	}
+	if err := d.Fill(epd.WHITE); !errors.Is(err, epd.ErrInvalidState) {
+		t.Errorf("Fill before Init: err = %v", err)
+	}
+	if err := d.Init(); err != nil {
+		t.Fatal(err)
+	}
+	bus.Fail = true
+	if err := d.Display(); !errors.Is(err, epdtest.ErrBus) {
+		t.Errorf("Display with a failing bus: err = %v", err)
+	}
+}
@@ -12,12 +12,37 @@ import (
 // Type FrameBuffer implements a framebuffer using the MHMSBFormat
 type FrameBuffer struct {
 	Buf      *[]byte
+	Store    Storage // Used instead of Buf when set
 	Width    int
 	Height   int
 	Stride   int
 	Rotation int // Can only be one of (0, 1, 2, 3)
 }
 
+// Type Storage is backing memory for a FrameBuffer that doesn't live in MCU
+// RAM, such as an external SPI SRAM
+type Storage interface {
+	Get(index int) byte
+	Set(index int, val byte)
+	Fill(val byte)
+	Len() int
+}
+
+func (f *FrameBuffer) get(index int) byte {
+	if f.Store != nil {
+		return f.Store.Get(index)
+	}
+	return (*f.Buf)[index]
+}
+
+func (f *FrameBuffer) set(index int, val byte) {
+	if f.Store != nil {
+		f.Store.Set(index, val)
+		return
+	}
+	(*f.Buf)[index] = val
+}
+
 // Func SetRotation sets the rotation of the framebuffer
 func (f *FrameBuffer) SetRotation(val int) error {
 	if val > 3 {
@@ -30,7 +55,7 @@ func (f *FrameBuffer) SetRotation(val int) error {
 func getPixel(fb *FrameBuffer, x, y int) byte {
 	index := (y*fb.Stride + x) / 8
 	offset := 7 - x&0x07
-	return ((*fb.Buf)[index] >> offset) & 0x01
+	return (fb.get(index) >> offset) & 0x01
 }
 
 func setPixel(fb *FrameBuffer, x, y, color int) {
@@ -40,7 +65,7 @@ func setPixel(fb *FrameBuffer, x, y, color int) {
 	if color != 0 {
 		colorBit = 1
 	}
-	(*fb.Buf)[index] = ((*fb.Buf)[index] & ^(0x01 << offset)) | (colorBit << offset)
+	fb.set(index, (fb.get(index) & ^(0x01<<offset))|(colorBit<<offset))
 }
 
 // Func Pixel will get the value of a pixel if you don't pass a color
@@ -75,12 +100,14 @@ func (f *FrameBuffer) Pixel(x, y int, color ...int) byte {
 }
 
 func (f *FrameBuffer) Clear() {
-	for i := range *f.Buf {
-		(*f.Buf)[i] = 0x00
-	}
+	f.Fill(0x00)
 }
 
 func (f *FrameBuffer) Fill(color uint8) {
+	if f.Store != nil {
+		f.Store.Fill(color)
+		return
+	}
 	for i := 0; i < len((*f.Buf)); i++ {
 		(*f.Buf)[i] = color
 	}
@@ -91,7 +118,7 @@ func (f *FrameBuffer) FillRect(x, y, width, height int, color uint8) {
 		offset := 7 - (_x & 0x07)
 		for _y := y; _y < (y + height); _y++ {
 			index := (_y*f.Stride + _x) / 8
-			(*f.Buf)[index] = ((*f.Buf)[index] & ^(0x01 << offset)) | (color << offset)
+			f.set(index, (f.get(index) & ^(0x01<<offset))|(color<<offset))
 		}
 	}
 }
@@ -0,0 +1,98 @@
+package il0373
+
+import "sync"
+
+// Type asyncState tracks the background refresh started by DisplayAsync.
+// Frames are double buffered: pending holds the latest frame requested while
+// a refresh is in flight, inflight holds the frame currently being uploaded.
+type asyncState struct {
+	mu         sync.Mutex
+	running    bool
+	hasPending bool
+
+	pending1  []byte
+	pending2  []byte
+	inflight1 []byte
+	inflight2 []byte
+
+	// Waiters for the pending frame, all of them get the result of the
+	// refresh that finally puts it on the panel
+	waiters []chan error
+}
+
+// Func DisplayAsync snapshots the framebuffers and refreshes the panel in a
+// goroutine, returning straight away. The returned channel receives the result
+// of the refresh once it has finished and is then closed.
+//
+// If a refresh is already running the frame is queued behind it. Queued frames
+// are coalesced: calling DisplayAsync several times while the panel is busy
+// only uploads the latest frame, and every caller is notified when it lands.
+//
+// When the framebuffers live in SRAM no snapshot is taken, the refresh streams
+// whatever the SRAM holds when it starts and drawing blocks until it's done.
+// When DoubleBuffered the front buffers are snapshotted, call Present first.
+// Before Initialize the channel receives ErrInvalidState.
+func (d *Device) DisplayAsync() <-chan error {
+	done := make(chan error, 1)
+	if !d.ready() {
+		done <- ErrInvalidState
+		close(done)
+		return done
+	}
+
+	a := &d.async
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	// Sized on first use, and again if Initialize has changed the planes
+	if d.sram == nil && len(a.pending1) != len(d.front1) {
+		a.pending1 = make([]byte, len(d.front1))
+		a.pending2 = make([]byte, len(d.front2))
+		a.inflight1 = make([]byte, len(d.front1))
+		a.inflight2 = make([]byte, len(d.front2))
+	}
+
+	// Replace whatever was queued with the current contents
+	copy(a.pending1, d.front1)
+	copy(a.pending2, d.front2)
+	a.hasPending = true
+	a.waiters = append(a.waiters, done)
+
+	if !a.running {
+		a.running = true
+		go d.asyncLoop()
+	}
+	return done
+}
+
+// Func Busy reports whether a background refresh is running or queued
+func (d *Device) Busy() bool {
+	d.async.mu.Lock()
+	defer d.async.mu.Unlock()
+	return d.async.running
+}
+
+func (d *Device) asyncLoop() {
+	a := &d.async
+	for {
+		a.mu.Lock()
+		if !a.hasPending {
+			a.running = false
+			a.mu.Unlock()
+			return
+		}
+		a.pending1, a.inflight1 = a.inflight1, a.pending1
+		a.pending2, a.inflight2 = a.inflight2, a.pending2
+		buffer1, buffer2 := a.inflight1, a.inflight2
+		waiters := a.waiters
+		a.waiters = nil
+		a.hasPending = false
+		a.mu.Unlock()
+
+		err := d.display(buffer1, buffer2)
+		for _, w := range waiters {
+			w <- err
+			close(w)
+		}
+	}
+}
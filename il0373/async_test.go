@@ -0,0 +1,66 @@
+package il0373_test
+
+import (
+	"testing"
+
+	"github.com/davidadeleon/gophercon2022Badge/il0373"
+	"github.com/davidadeleon/gophercon2022Badge/il0373/il0373test"
+)
+
+func TestDisplayAsyncBeforeInitialize(t *testing.T) {
+	t.Parallel()
+	e := il0373test.NewEmulator(width, height)
+	d := e.Device()
+
+	for i := 0; i < 2; i++ {
+		if err := <-d.DisplayAsync(); err != il0373.ErrInvalidState {
+			t.Fatalf("DisplayAsync %d before Initialize: err = %v", i, err)
+		}
+	}
+
+	// Once initialized the same Device refreshes normally
+	if err := d.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	d.Fill(il0373.BLACK)
+	if err := <-d.DisplayAsync(); err != nil {
+		t.Fatal(err)
+	}
+	checkViolations(t, e)
+	checkPixel(t, e, 0, 0, black)
+}
+
+func TestDisplayAsyncCoalesces(t *testing.T) {
+	t.Parallel()
+	e, d := newPanel(t)
+
+	d.Fill(il0373.BLACK)
+	first := d.DisplayAsync()
+	// Queued behind the first refresh, only the last of these is uploaded
+	var queued []<-chan error
+	for _, fill := range []int{il0373.RED, il0373.BLACK, il0373.WHITE} {
+		d.Fill(fill)
+		queued = append(queued, d.DisplayAsync())
+	}
+	if !d.Busy() {
+		t.Error("Busy = false with refreshes queued")
+	}
+
+	if err := <-first; err != nil {
+		t.Fatal(err)
+	}
+	for i, done := range queued {
+		if err := <-done; err != nil {
+			t.Fatalf("queued frame %d: %v", i, err)
+		}
+		if _, ok := <-done; ok {
+			t.Errorf("queued frame %d: channel not closed", i)
+		}
+	}
+	checkViolations(t, e)
+
+	if e.Refreshes > 2 {
+		t.Errorf("%d refreshes, want the queued frames coalesced into one", e.Refreshes)
+	}
+	checkPixel(t, e, 10, 10, white)
+}
@@ -0,0 +1,69 @@
+package il0373_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/davidadeleon/gophercon2022Badge/il0373"
+	"github.com/davidadeleon/gophercon2022Badge/il0373/il0373test"
+)
+
+const planeSize = width * height / 8
+
+// Func countDisplay returns the number of Tx calls a full refresh makes with
+// the given chunk size
+func countDisplay(t testing.TB, chunkSize int) int {
+	t.Helper()
+	e, d := newPanel(t)
+	bus := &il0373test.SPI{Bus: e}
+	d.SPI = bus
+	d.ChunkSize = chunkSize
+	d.Fill(il0373.RED)
+	if err := d.Display(); err != nil {
+		t.Fatal(err)
+	}
+	checkViolations(t, e)
+	checkPixel(t, e, width-1, height-1, red)
+	return bus.Calls
+}
+
+func TestDisplayTxCalls(t *testing.T) {
+	t.Parallel()
+
+	// Sending each plane in one transfer leaves the per command overhead
+	overhead := countDisplay(t, planeSize) - 2
+
+	for _, tc := range []struct {
+		chunkSize int
+		perPlane  int
+	}{
+		{il0373.DefaultChunkSize, (planeSize + il0373.DefaultChunkSize - 1) / il0373.DefaultChunkSize},
+		{1, planeSize},
+	} {
+		tc := tc
+		t.Run(strconv.Itoa(tc.chunkSize), func(t *testing.T) {
+			t.Parallel()
+			if got, want := countDisplay(t, tc.chunkSize), overhead+2*tc.perPlane; got != want {
+				t.Errorf("%d Tx calls, want %d", got, want)
+			}
+		})
+	}
+}
+
+func BenchmarkDisplay(b *testing.B) {
+	for _, chunkSize := range []int{1, 32, il0373.DefaultChunkSize, planeSize} {
+		b.Run(strconv.Itoa(chunkSize), func(b *testing.B) {
+			e, d := newPanel(b)
+			bus := &il0373test.SPI{Bus: e}
+			d.SPI = bus
+			d.ChunkSize = chunkSize
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := d.Display(); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(bus.Calls)/float64(b.N), "tx/op")
+		})
+	}
+}
@@ -0,0 +1,254 @@
+package il0373
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrInvalidConfig = errors.New("il0373: invalid panel config")
+
+// Type ResolutionSource selects where the controller takes the panel size
+// from: the RES bits of PANEL_SETTING or the RESOLUTION register
+type ResolutionSource uint8
+
+const (
+	// ResolutionRegister sends the Device's Width and Height in RESOLUTION
+	ResolutionRegister ResolutionSource = iota
+	Resolution96x230
+	Resolution96x252
+	Resolution128x296
+	Resolution160x296
+)
+
+// Type BorderColor is what the controller drives the border around the active
+// area to on a refresh
+type BorderColor uint8
+
+const (
+	BorderBlack BorderColor = iota
+	BorderWhite
+	BorderRed
+	// BorderFloating leaves the border undriven so it keeps its last color
+	BorderFloating
+)
+
+// Type DataPolarity selects which bit value in each RAM plane inks a pixel.
+// PolarityNormal is a 0 bit in both planes. The framebuffers follow it, so
+// drawing is the same either way.
+type DataPolarity uint8
+
+const (
+	PolarityNormal      DataPolarity = 0
+	PolarityInvertBlack DataPolarity = 1 << 0
+	PolarityInvertRed   DataPolarity = 1 << 1
+)
+
+// Type BoosterPhase configures one phase of the booster soft start
+type BoosterPhase struct {
+	// Period is the soft start period, 10, 20, 30 or 40ms
+	Period time.Duration
+	// Strength is the driving strength, 1 (weakest) to 8
+	Strength uint8
+	// MinOffTime selects the minimum GDR off time, 0 (0.27us) to 7 (6.58us)
+	MinOffTime uint8
+}
+
+// Type PanelConfig is everything PowerUp writes to the controller. Voltages
+// are in millivolts and are magnitudes, the controller picks the sign.
+type PanelConfig struct {
+	// POWER_SETTING
+	GateVoltage      int // VGH/VGL, 13000 to 16000 in 1000 steps
+	SourceVoltage    int // VDH/VDL for black/white, 2400 to 11000 in 200 steps
+	SourceVoltageRed int // VDHR for red, 2400 to 11000 in 200 steps
+
+	// BOOSTER_SOFT_START, phases A, B and C
+	Booster [3]BoosterPhase
+
+	// PLL sets the frame rate from the M and N dividers, 1 to 7 each.
+	// M=5 N=1 gives 50Hz.
+	PLLM uint8
+	PLLN uint8
+
+	// VCM_DC_SETTING, 100 to 3000 in 50 steps
+	VCOM int
+
+	// PANEL_SETTING scan direction, gates scanning up and sources shifting
+	// right is the panel's native orientation
+	ScanUp     bool
+	ShiftRight bool
+	// Monochrome drives the panel in black/white mode, ignoring the red plane
+	Monochrome bool
+	// LUTFromRegister uses the waveform uploaded to the LUT registers instead
+	// of the one in OTP
+	LUTFromRegister bool
+	Resolution      ResolutionSource
+
+	// VCOM_AND_DATA_INTERVAL (CDI). Border is applied on every refresh and
+	// floated on power down. BorderRed needs the tri-color mode.
+	// DataInterval is the VCOM to data interval in hsync periods, 2 to 17.
+	Border       BorderColor
+	DataPolarity DataPolarity
+	DataInterval int
+}
+
+// Func DefaultPanelConfig returns the settings for the Adafruit 2.9" tri-color
+// FeatherWing at room temperature
+func DefaultPanelConfig() PanelConfig {
+	phase := BoosterPhase{Period: 10 * time.Millisecond, Strength: 3, MinOffTime: 7}
+	return PanelConfig{
+		GateVoltage:      16000,
+		SourceVoltage:    11000,
+		SourceVoltageRed: 4200,
+		Booster:          [3]BoosterPhase{phase, phase, phase},
+		PLLM:             5,
+		PLLN:             1,
+		VCOM:             600,
+		ScanUp:           true,
+		ShiftRight:       true,
+		Resolution:       ResolutionRegister,
+		Border:           BorderBlack,
+		DataPolarity:     PolarityNormal,
+		DataInterval:     10,
+	}
+}
+
+// Type Registers holds the encoded data bytes for each command PowerUp sends
+type Registers struct {
+	PowerSetting     [5]byte
+	BoosterSoftStart [3]byte
+	PanelSetting     byte
+	CDI              byte
+	PLL              byte
+	// CDIPowerOff is sent before POWER_OFF, the vendor's 0x17 at the
+	// default data interval, as the Adafruit driver sends
+	CDIPowerOff byte
+	VCMDC       byte
+	// Resolution is only sent when SendResolution is set
+	Resolution     [3]byte
+	SendResolution bool
+}
+
+// In tri-color mode the border is VBD with DDX=x1: 00 black, 01 white, 10
+// red, 11 floating. In black/white mode DDX is 0x and VBD is 00 floating, 01
+// black and 10 white. A DDX bit of 0 inverts that plane's data.
+var monoBorder = [...]byte{BorderBlack: 0x1, BorderWhite: 0x2, BorderFloating: 0x0}
+
+func (c PanelConfig) cdi(border BorderColor) byte {
+	vbd := byte(border)
+	ddx := byte(0x3 &^ c.DataPolarity)
+	if c.Monochrome {
+		vbd = monoBorder[border]
+		ddx &= 0x1
+	}
+	return vbd<<6 | ddx<<4 | byte(17-c.DataInterval)
+}
+
+// Func cdiPowerOff is VBD 00 and DDX 01 whatever the config, from the
+// vendor's power off sequence
+func (c PanelConfig) cdiPowerOff() byte {
+	return 0x1<<4 | byte(17-c.DataInterval)
+}
+
+func invalid(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: "+format, append([]interface{}{ErrInvalidConfig}, args...)...)
+}
+
+// Func Validate checks every field is in range for the controller
+func (c PanelConfig) Validate() error {
+	if c.GateVoltage < 13000 || c.GateVoltage > 16000 || c.GateVoltage%1000 != 0 {
+		return invalid("gate voltage %dmV", c.GateVoltage)
+	}
+	for _, v := range []int{c.SourceVoltage, c.SourceVoltageRed} {
+		if v < 2400 || v > 11000 || (v-2400)%200 != 0 {
+			return invalid("source voltage %dmV", v)
+		}
+	}
+	for i, p := range c.Booster {
+		if p.Period < 10*time.Millisecond || p.Period > 40*time.Millisecond || p.Period%(10*time.Millisecond) != 0 {
+			return invalid("booster phase %d period %s", i, p.Period)
+		}
+		if p.Strength < 1 || p.Strength > 8 {
+			return invalid("booster phase %d strength %d", i, p.Strength)
+		}
+		if p.MinOffTime > 7 {
+			return invalid("booster phase %d off time %d", i, p.MinOffTime)
+		}
+	}
+	if c.PLLM < 1 || c.PLLM > 7 || c.PLLN < 1 || c.PLLN > 7 {
+		return invalid("pll M=%d N=%d", c.PLLM, c.PLLN)
+	}
+	if c.VCOM < 100 || c.VCOM > 3000 || c.VCOM%50 != 0 {
+		return invalid("vcom %dmV", c.VCOM)
+	}
+	if c.Resolution > Resolution160x296 {
+		return invalid("resolution source %d", c.Resolution)
+	}
+	if c.Border > BorderFloating || (c.Monochrome && c.Border == BorderRed) {
+		return invalid("border %d", c.Border)
+	}
+	if c.DataPolarity > PolarityInvertBlack|PolarityInvertRed {
+		return invalid("data polarity %d", c.DataPolarity)
+	}
+	if c.DataInterval < 2 || c.DataInterval > 17 {
+		return invalid("data interval %d", c.DataInterval)
+	}
+	return nil
+}
+
+// Func Encode validates the config and encodes it for a width x height panel
+func (c PanelConfig) Encode(width, height int) (Registers, error) {
+	var r Registers
+	if err := c.Validate(); err != nil {
+		return r, err
+	}
+
+	// Internal DC/DC for both source and gate
+	r.PowerSetting[0] = 0x03
+	r.PowerSetting[1] = byte((16000 - c.GateVoltage) / 1000)
+	r.PowerSetting[2] = byte((c.SourceVoltage - 2400) / 200)
+	r.PowerSetting[3] = r.PowerSetting[2]
+	r.PowerSetting[4] = byte((c.SourceVoltageRed - 2400) / 200)
+
+	for i, p := range c.Booster {
+		r.BoosterSoftStart[i] = byte(p.Period/(10*time.Millisecond)-1)<<6 | (p.Strength-1)<<3 | p.MinOffTime
+	}
+
+	// Booster on and not in soft reset
+	panel := byte(0x03)
+	switch c.Resolution {
+	case ResolutionRegister, Resolution160x296:
+		panel |= 0x3 << 6
+	case Resolution128x296:
+		panel |= 0x2 << 6
+	case Resolution96x252:
+		panel |= 0x1 << 6
+	}
+	if c.LUTFromRegister {
+		panel |= 1 << 5
+	}
+	if c.Monochrome {
+		panel |= 1 << 4
+	}
+	if c.ScanUp {
+		panel |= 1 << 3
+	}
+	if c.ShiftRight {
+		panel |= 1 << 2
+	}
+	r.PanelSetting = panel
+
+	r.CDI = c.cdi(c.Border)
+	r.CDIPowerOff = c.cdiPowerOff()
+	r.PLL = c.PLLM<<3 | c.PLLN
+	r.VCMDC = byte((c.VCOM - 100) / 50)
+
+	if c.Resolution == ResolutionRegister {
+		if width <= 0 || width > 0xFF || height <= 0 || height > 0x1FF {
+			return r, ErrBadDimensions
+		}
+		r.Resolution = [3]byte{byte(width & 0xFF), byte((height >> 8) & 0xFF), byte(height & 0xFF)}
+		r.SendResolution = true
+	}
+	return r, nil
+}
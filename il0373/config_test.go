@@ -0,0 +1,107 @@
+package il0373_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/davidadeleon/gophercon2022Badge/il0373"
+)
+
+func TestDefaultPanelConfig(t *testing.T) {
+	t.Parallel()
+	regs, err := il0373.DefaultPanelConfig().Encode(width, height)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := il0373.Registers{
+		PowerSetting:     [5]byte{0x03, 0x00, 0x2B, 0x2B, 0x09},
+		BoosterSoftStart: [3]byte{0x17, 0x17, 0x17},
+		PanelSetting:     0xCF,
+		CDI:              0x37,
+		PLL:              0x29,
+		CDIPowerOff:      0x17,
+		VCMDC:            0x0A,
+		Resolution:       [3]byte{128, 0x01, 0x28},
+		SendResolution:   true,
+	}
+	if regs != want {
+		t.Errorf("Encode = %+v\nwant %+v", regs, want)
+	}
+
+	// And that's what goes over the wire
+	e, d := newPanel(t)
+	if err := d.Display(); err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range []struct {
+		name      string
+		got, want []byte
+	}{
+		{"POWER_SETTING", e.PowerSetting, want.PowerSetting[:]},
+		{"BOOSTER_SOFT_START", e.BoosterSoftStart, want.BoosterSoftStart[:]},
+		{"PANEL_SETTING", e.PanelSetting, []byte{want.PanelSetting}},
+		{"CDI", []byte{e.CDI}, []byte{want.CDI}},
+		{"PLL", []byte{e.PLL}, []byte{want.PLL}},
+		{"VCM_DC_SETTING", []byte{e.VCMDC}, []byte{want.VCMDC}},
+	} {
+		if !bytes.Equal(r.got, r.want) {
+			t.Errorf("%s sent % x, want % x", r.name, r.got, r.want)
+		}
+	}
+	if err := d.PowerDown(); err != nil {
+		t.Fatal(err)
+	}
+	checkViolations(t, e)
+	if e.CDI != want.CDIPowerOff {
+		t.Errorf("CDI before POWER_OFF = 0x%02x, want 0x%02x", e.CDI, want.CDIPowerOff)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		name  string
+		edit  func(c *il0373.PanelConfig)
+		valid bool
+	}{
+		{"default", func(c *il0373.PanelConfig) {}, true},
+		{"gate step", func(c *il0373.PanelConfig) { c.GateVoltage = 14500 }, false},
+		{"gate range", func(c *il0373.PanelConfig) { c.GateVoltage = 12000 }, false},
+		{"source range", func(c *il0373.PanelConfig) { c.SourceVoltage = 11200 }, false},
+		{"source step", func(c *il0373.PanelConfig) { c.SourceVoltage = 2500 }, false},
+		{"red source range", func(c *il0373.PanelConfig) { c.SourceVoltageRed = 2200 }, false},
+		{"booster period step", func(c *il0373.PanelConfig) { c.Booster[1].Period = 15 * time.Millisecond }, false},
+		{"booster period range", func(c *il0373.PanelConfig) { c.Booster[2].Period = 50 * time.Millisecond }, false},
+		{"booster strength", func(c *il0373.PanelConfig) { c.Booster[0].Strength = 0 }, false},
+		{"pll M 0", func(c *il0373.PanelConfig) { c.PLLM = 0 }, false},
+		{"pll N 0", func(c *il0373.PanelConfig) { c.PLLN = 0 }, false},
+		{"vcom step", func(c *il0373.PanelConfig) { c.VCOM = 625 }, false},
+		{"red border in monochrome", func(c *il0373.PanelConfig) {
+			c.Monochrome = true
+			c.Border = il0373.BorderRed
+		}, false},
+		{"red border in tri-color", func(c *il0373.PanelConfig) { c.Border = il0373.BorderRed }, true},
+		{"interval 1", func(c *il0373.PanelConfig) { c.DataInterval = 1 }, false},
+		{"interval 2", func(c *il0373.PanelConfig) { c.DataInterval = 2 }, true},
+		{"interval 17", func(c *il0373.PanelConfig) { c.DataInterval = 17 }, true},
+		{"interval 18", func(c *il0373.PanelConfig) { c.DataInterval = 18 }, false},
+	} {
+		c := il0373.DefaultPanelConfig()
+		tc.edit(&c)
+		err := c.Validate()
+		if tc.valid {
+			if err != nil {
+				t.Errorf("%s: %v", tc.name, err)
+			}
+			continue
+		}
+		if !errors.Is(err, il0373.ErrInvalidConfig) {
+			t.Errorf("%s: Validate = %v, want ErrInvalidConfig", tc.name, err)
+		}
+		if _, err := c.Encode(width, height); !errors.Is(err, il0373.ErrInvalidConfig) {
+			t.Errorf("%s: Encode = %v, want ErrInvalidConfig", tc.name, err)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package il0373_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/davidadeleon/gophercon2022Badge/epd"
+	"github.com/davidadeleon/gophercon2022Badge/il0373"
+	"github.com/davidadeleon/gophercon2022Badge/il0373/il0373test"
+)
+
+const (
+	width  = 128
+	height = 296
+)
+
+var (
+	white = color.RGBA{0xFF, 0xFF, 0xFF, 0xFF}
+	black = color.RGBA{0x00, 0x00, 0x00, 0xFF}
+	red   = color.RGBA{0xFF, 0x00, 0x00, 0xFF}
+)
+
+// Func newPanel returns an initialized Device wired to an emulated badge panel
+func newPanel(t testing.TB) (*il0373test.Emulator, *il0373.Device) {
+	t.Helper()
+	e := il0373test.NewEmulator(width, height)
+	d := e.Device()
+	if err := d.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	return e, d
+}
+
+func checkViolations(t testing.TB, e *il0373test.Emulator) {
+	t.Helper()
+	for _, v := range e.Violations {
+		t.Error(v)
+	}
+}
+
+func checkPixel(t testing.TB, e *il0373test.Emulator, x, y int, want color.RGBA) {
+	t.Helper()
+	if got := e.At(x, y); got != want {
+		t.Errorf("panel at %d,%d = %v, want %v", x, y, got, want)
+	}
+}
+
+func TestDisplay(t *testing.T) {
+	t.Parallel()
+	e, d := newPanel(t)
+	d.Fill(il0373.WHITE)
+	for x := 0; x < 50; x++ {
+		d.Pixel(x, 10, il0373.BLACK)
+		d.Pixel(x, 20, il0373.RED)
+	}
+	if err := d.Display(); err != nil {
+		t.Fatal(err)
+	}
+	checkViolations(t, e)
+
+	if !e.CS.Configured || e.CS.Mode != epd.PinOutput || !e.BUSY.Configured || e.BUSY.Mode != epd.PinInput {
+		t.Errorf("pins not set up, CS %+v BUSY %+v", e.CS, e.BUSY)
+	}
+	if e.Refreshes != 1 || e.PoweredOn != true {
+		t.Errorf("refreshes = %d, powered on = %v", e.Refreshes, e.PoweredOn)
+	}
+	if e.ResWidth != width || e.ResHeight != height {
+		t.Errorf("resolution = %dx%d", e.ResWidth, e.ResHeight)
+	}
+	checkPixel(t, e, 0, 10, black)
+	checkPixel(t, e, 49, 10, black)
+	checkPixel(t, e, 50, 10, white)
+	checkPixel(t, e, 5, 20, red)
+	checkPixel(t, e, 5, 15, white)
+
+	if err := d.Sleep(); err != nil {
+		t.Fatal(err)
+	}
+	checkViolations(t, e)
+	if e.PoweredOn {
+		t.Error("panel still powered after Sleep")
+	}
+}
+
+func TestFill(t *testing.T) {
+	t.Parallel()
+	e, d := newPanel(t)
+	for _, tc := range []struct {
+		fill int
+		want color.RGBA
+	}{
+		{il0373.BLACK, black},
+		{il0373.RED, red},
+		{il0373.WHITE, white},
+	} {
+		d.Fill(tc.fill)
+		if err := d.Display(); err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range []image.Point{{0, 0}, {width - 1, 0}, {0, height - 1}, {width - 1, height - 1}, {63, 147}} {
+			checkPixel(t, e, p.X, p.Y, tc.want)
+		}
+	}
+	checkViolations(t, e)
+}
+
+func TestDisplayImage(t *testing.T) {
+	t.Parallel()
+	e, d := newPanel(t)
+	d.Fill(il0373.WHITE)
+
+	// Black at 0,0 and 9,1, red at 1,1 that wins over black at 9,0
+	bitmap := &il0373.Bitmap{
+		Width:  10,
+		Height: 2,
+		Black:  []byte{0x80, 0x40, 0x00, 0x40},
+		Red:    []byte{0x00, 0x40, 0x40, 0x00},
+	}
+	if err := d.DisplayImage(8, 3, bitmap); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.DisplayImage(width-4, 3, bitmap); err != il0373.ErrBadDimensions {
+		t.Errorf("image off the edge: err = %v", err)
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	img.Set(0, 0, black)
+	img.Set(1, 0, white)
+	img.Set(0, 1, white)
+	img.Set(1, 1, red)
+	if err := d.DisplayImage(0, 100, img); err != nil {
+		t.Fatal(err)
+	}
+
+	// Rotated a quarter turn logical x, y lands on the panel at W-1-y, x
+	if err := d.SetRotation(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.DisplayImage(200, 0, img); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := d.Display(); err != nil {
+		t.Fatal(err)
+	}
+	checkViolations(t, e)
+
+	checkPixel(t, e, 8, 3, black)
+	checkPixel(t, e, 9, 3, white)
+	checkPixel(t, e, 17, 3, red)
+	checkPixel(t, e, 9, 4, red)
+	checkPixel(t, e, 17, 4, black)
+	checkPixel(t, e, 0, 100, black)
+	checkPixel(t, e, 1, 100, white)
+	checkPixel(t, e, 1, 101, red)
+	checkPixel(t, e, width-1, 200, black)
+	checkPixel(t, e, width-2, 201, red)
+}
+
+func TestWritePNG(t *testing.T) {
+	t.Parallel()
+	e, d := newPanel(t)
+	d.Fill(il0373.WHITE)
+	d.Pixel(3, 4, il0373.RED)
+	if err := d.Display(); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := e.WritePNG(&buf); err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != width || b.Dy() != height {
+		t.Fatalf("png is %v", b)
+	}
+	if c := color.RGBAModel.Convert(img.At(3, 4)); c != red {
+		t.Errorf("png at 3,4 = %v, want red", c)
+	}
+}
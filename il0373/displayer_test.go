@@ -0,0 +1,157 @@
+package il0373_test
+
+import (
+	"errors"
+	"image/color"
+	"testing"
+
+	"github.com/davidadeleon/gophercon2022Badge/il0373"
+	"tinygo.org/x/tinydraw"
+)
+
+// Func panelXY maps a point in rotation rot to the unrotated panel
+func panelXY(rot, x, y int) (int, int) {
+	switch rot {
+	case 1:
+		return width - 1 - y, x
+	case 2:
+		return width - 1 - x, height - 1 - y
+	case 3:
+		return y, height - 1 - x
+	}
+	return x, y
+}
+
+func TestSize(t *testing.T) {
+	t.Parallel()
+	_, d := newPanel(t)
+	for rot, want := range [][2]int16{{width, height}, {height, width}, {width, height}, {height, width}} {
+		if err := d.SetRotation(rot); err != nil {
+			t.Fatal(err)
+		}
+		if w, h := d.Size(); w != want[0] || h != want[1] {
+			t.Errorf("rotation %d: size %dx%d, want %dx%d", rot, w, h, want[0], want[1])
+		}
+	}
+}
+
+func TestSetPixel(t *testing.T) {
+	t.Parallel()
+	e, d := newPanel(t)
+	d.Fill(il0373.WHITE)
+	d.AlphaThreshold = 0x80
+	for i, tc := range []struct {
+		c    color.RGBA
+		want color.RGBA
+	}{
+		{black, black},
+		{red, red},
+		{white, white},
+		{color.RGBA{0x20, 0x20, 0x20, 0xFF}, black},
+		{color.RGBA{0xE0, 0x10, 0x20, 0xFF}, red},
+		{color.RGBA{0xE0, 0xE0, 0xD0, 0xFF}, white},
+		// Below AlphaThreshold, left white
+		{color.RGBA{0x00, 0x00, 0x00, 0x40}, white},
+	} {
+		d.SetPixel(int16(i), 0, tc.c)
+		d.SetPixel(int16(i), 1, black)
+		d.SetPixel(int16(i), 1, tc.c)
+	}
+	if err := d.Display(); err != nil {
+		t.Fatal(err)
+	}
+	checkViolations(t, e)
+	checkPixel(t, e, 0, 0, black)
+	checkPixel(t, e, 1, 0, red)
+	checkPixel(t, e, 2, 0, white)
+	checkPixel(t, e, 3, 0, black)
+	checkPixel(t, e, 4, 0, red)
+	checkPixel(t, e, 5, 0, white)
+	checkPixel(t, e, 6, 0, white)
+	// Drawing over a pixel replaces it, a transparent one doesn't
+	checkPixel(t, e, 1, 1, red)
+	checkPixel(t, e, 2, 1, white)
+	checkPixel(t, e, 6, 1, black)
+}
+
+func TestFillRectangle(t *testing.T) {
+	t.Parallel()
+	e, d := newPanel(t)
+	d.Fill(il0373.WHITE)
+	if err := d.SetRotation(1); err != nil {
+		t.Fatal(err)
+	}
+
+	// Hanging off the top left and bottom right corners
+	if err := d.FillRectangle(-10, -10, 20, 20, black); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.FillRectangle(height-5, width-5, 100, 100, red); err != nil {
+		t.Fatal(err)
+	}
+	// Entirely off the panel, either way
+	for _, r := range [][4]int16{{30000, 30000, 10, 10}, {-32768, -32768, 32767, 32767}, {-20, 0, 10, 10}} {
+		if err := d.FillRectangle(r[0], r[1], r[2], r[3], black); err != nil {
+			t.Errorf("FillRectangle %v: %v", r, err)
+		}
+	}
+	for _, r := range [][2]int16{{0, 5}, {5, 0}, {-1, 5}} {
+		if err := d.FillRectangle(0, 0, r[0], r[1], black); !errors.Is(err, il0373.ErrBadDimensions) {
+			t.Errorf("FillRectangle %dx%d: err = %v", r[0], r[1], err)
+		}
+	}
+
+	if err := d.Display(); err != nil {
+		t.Fatal(err)
+	}
+	checkViolations(t, e)
+	for _, tc := range []struct {
+		x, y int
+		want color.RGBA
+	}{
+		{0, 0, black}, {9, 9, black}, {10, 9, white}, {9, 10, white},
+		{height - 5, width - 5, red}, {height - 1, width - 1, red}, {height - 6, width - 5, white},
+		{100, 60, white},
+	} {
+		x, y := panelXY(1, tc.x, tc.y)
+		checkPixel(t, e, x, y, tc.want)
+	}
+}
+
+func TestShapes(t *testing.T) {
+	t.Parallel()
+	for rot := 0; rot < 4; rot++ {
+		rot := rot
+		t.Run("", func(t *testing.T) {
+			t.Parallel()
+			e, d := newPanel(t)
+			d.Fill(il0373.WHITE)
+			if err := d.SetRotation(rot); err != nil {
+				t.Fatal(err)
+			}
+			// A rectangle outline, a diagonal and a circle
+			if err := tinydraw.Rectangle(d, 10, 10, 51, 31, black); err != nil {
+				t.Fatal(err)
+			}
+			tinydraw.Line(d, 70, 10, 100, 40, red)
+			tinydraw.Circle(d, 40, 80, 20, red)
+			if err := d.Display(); err != nil {
+				t.Fatal(err)
+			}
+			checkViolations(t, e)
+
+			for _, tc := range []struct {
+				x, y int
+				want color.RGBA
+			}{
+				{10, 10, black}, {35, 10, black}, {60, 25, black}, {60, 40, black}, {10, 25, black},
+				{35, 25, white}, {9, 10, white}, {61, 40, white},
+				{70, 10, red}, {85, 25, red}, {100, 40, red}, {85, 24, white},
+				{40, 60, red}, {40, 100, red}, {20, 80, red}, {60, 80, red}, {40, 80, white},
+			} {
+				x, y := panelXY(rot, tc.x, tc.y)
+				checkPixel(t, e, x, y, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package il0373
+
+import (
+	"strconv"
+
+	"github.com/davidadeleon/gophercon2022Badge/epd"
+)
+
+// The errors are the epd ones, so code written against epd.Device can test
+// for them whichever panel it's driving
+var (
+	// ErrBusyTimeout is returned when the BUSY pin doesn't release within
+	// Device.BusyTimeout
+	ErrBusyTimeout = epd.ErrBusyTimeout
+
+	// ErrInvalidState is returned when an operation is attempted before the
+	// device is ready for it, e.g. Display before Initialize
+	ErrInvalidState = epd.ErrInvalidState
+
+	// ErrBadDimensions is returned when a width, height or buffer doesn't fit
+	// the panel
+	ErrBadDimensions = epd.ErrBadDimensions
+)
+
+// Type BusError is returned when a transfer on the SPI bus fails. Cmd is the
+// command that was being sent, or whose data was being sent, when it failed.
+type BusError struct {
+	Cmd byte
+	Err error
+}
+
+func (e *BusError) Error() string {
+	return "il0373: spi error on command 0x" + strconv.FormatUint(uint64(e.Cmd), 16) + ": " + e.Err.Error()
+}
+
+func (e *BusError) Unwrap() error {
+	return e.Err
+}
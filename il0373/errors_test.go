@@ -0,0 +1,196 @@
+package il0373_test
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"testing"
+	"time"
+
+	"github.com/davidadeleon/gophercon2022Badge/epd"
+	"github.com/davidadeleon/gophercon2022Badge/il0373"
+	"github.com/davidadeleon/gophercon2022Badge/il0373/il0373test"
+)
+
+var errBus = errors.New("bus fault")
+
+// Func failingPanel returns a panel whose SPI bus fails every transfer after
+// the first failAfter
+func failingPanel(t *testing.T, failAfter int) (*il0373test.Emulator, *il0373.Device, *il0373test.SPI) {
+	t.Helper()
+	e, d := newPanel(t)
+	bus := &il0373test.SPI{Bus: e, Err: errBus, FailAfter: failAfter}
+	d.SPI = bus
+	return e, d, bus
+}
+
+func checkBusError(t *testing.T, err error, cmd byte) {
+	t.Helper()
+	var busErr *il0373.BusError
+	if !errors.As(err, &busErr) {
+		t.Fatalf("err = %v, want a *BusError", err)
+	}
+	if busErr.Cmd != cmd {
+		t.Errorf("BusError.Cmd = 0x%02x, want 0x%02x", busErr.Cmd, cmd)
+	}
+	if !errors.Is(err, errBus) {
+		t.Errorf("err = %v doesn't wrap the bus error", err)
+	}
+}
+
+func TestPowerUpBusError(t *testing.T) {
+	t.Parallel()
+	e, d, _ := failingPanel(t, 0)
+	checkBusError(t, d.PowerUp(), il0373.IL0373_POWER_SETTING)
+	if !e.CS.Level {
+		t.Error("CS left asserted after a failed transfer")
+	}
+}
+
+func TestCommandBusError(t *testing.T) {
+	t.Parallel()
+	e, d, _ := failingPanel(t, 0)
+	_, err := d.WriteRam(0)
+	checkBusError(t, err, il0373.IL0373_DTM1)
+	if !e.CS.Level {
+		t.Error("CS left asserted after a failed transfer")
+	}
+}
+
+func TestDisplayBusError(t *testing.T) {
+	t.Parallel()
+
+	// Count the transfers of a good refresh to fail part way through one
+	_, good, bus := failingPanel(t, 0)
+	bus.Err = nil
+	good.Fill(il0373.WHITE)
+	if err := good.Display(); err != nil {
+		t.Fatal(err)
+	}
+	calls := bus.Calls
+
+	for _, tc := range []struct {
+		name      string
+		failAfter int
+		cmd       byte
+	}{
+		{"power setting", 0, il0373.IL0373_POWER_SETTING},
+		// The last transfers are the red plane data, then the refresh
+		{"plane data", calls - 5, il0373.IL0373_DTM2},
+		{"refresh", calls - 1, il0373.IL0373_DISPLAY_REFRESH},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			e, d, _ := failingPanel(t, tc.failAfter)
+			checkBusError(t, d.Display(), tc.cmd)
+			if !e.CS.Level {
+				t.Error("CS left asserted after a failed transfer")
+			}
+		})
+	}
+}
+
+func TestBusyTimeout(t *testing.T) {
+	t.Parallel()
+	e, d := newPanel(t)
+	d.BusyTimeout = 50 * time.Millisecond
+	e.BUSY.Level = true
+	if err := d.Display(); !errors.Is(err, il0373.ErrBusyTimeout) {
+		t.Fatalf("Display with BUSY stuck: err = %v", err)
+	}
+}
+
+func TestInvalidStateBeforeInitialize(t *testing.T) {
+	t.Parallel()
+	e := il0373test.NewEmulator(width, height)
+	d := e.Device()
+
+	errs := map[string]error{
+		"Display":       d.Display(),
+		"DisplayRegion": d.DisplayRegion(0, 0, 8, 8),
+		"DisplayImage":  d.DisplayImage(0, 0, image.NewRGBA(image.Rect(0, 0, 1, 1))),
+		"FillRectangle": d.FillRectangle(0, 0, 1, 1, color.RGBA{A: 0xFF}),
+		"SetRotation":   d.SetRotation(1),
+		"Clear":         d.Clear(),
+		"Fill":          d.Fill(il0373.WHITE),
+		"FillRect":      d.FillRect(0, 0, 8, 8, il0373.BLACK),
+		"Pixel":         d.Pixel(1, 1, il0373.RED),
+	}
+	// SetPixel has no error to return, it mustn't panic either
+	d.SetPixel(1, 1, color.RGBA{A: 0xFF})
+	_, errs["WriteRam"] = d.WriteRam(0)
+	for name, err := range errs {
+		if !errors.Is(err, il0373.ErrInvalidState) {
+			t.Errorf("%s before Initialize: err = %v", name, err)
+		}
+	}
+	if len(e.Violations) != 0 || e.Refreshes != 0 {
+		t.Errorf("panel touched before Initialize: %v", e.Violations)
+	}
+
+	if err := d.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.WriteRam(2); !errors.Is(err, il0373.ErrInvalidState) {
+		t.Errorf("WriteRam(2): err = %v", err)
+	}
+}
+
+func TestInitializeBadDimensions(t *testing.T) {
+	t.Parallel()
+	for _, size := range []image.Point{{0, height}, {width, 0}, {3, 5}} {
+		e := il0373test.NewEmulator(width, height)
+		d := e.Device()
+		d.Width, d.Height = size.X, size.Y
+		if err := d.Initialize(); !errors.Is(err, il0373.ErrBadDimensions) {
+			t.Errorf("Initialize %v: err = %v", size, err)
+		}
+	}
+}
+
+func TestSentinelsMatchEPD(t *testing.T) {
+	t.Parallel()
+	_, d := newPanel(t)
+	// The shared epd helpers report with the epd sentinels, which il0373's
+	// must match
+	err := d.DisplayRegion(width, 0, 8, 8)
+	if !errors.Is(err, il0373.ErrBadDimensions) || !errors.Is(err, epd.ErrBadDimensions) {
+		t.Errorf("DisplayRegion off the panel: err = %v", err)
+	}
+	if il0373.ErrInvalidState != epd.ErrInvalidState || il0373.ErrBusyTimeout != epd.ErrBusyTimeout {
+		t.Error("il0373 sentinels differ from epd's")
+	}
+}
+
+func TestPowerDownInvalidConfig(t *testing.T) {
+	t.Parallel()
+	e, d := newPanel(t)
+	if err := d.PowerUp(); err != nil {
+		t.Fatal(err)
+	}
+	regs, _ := d.Config.Encode(width, height)
+
+	// Broken after power up, the supplies must still be cut
+	d.Config.VCOM = 0
+	if err := d.PowerDown(); !errors.Is(err, il0373.ErrInvalidConfig) {
+		t.Errorf("PowerDown: err = %v, want ErrInvalidConfig", err)
+	}
+	if e.PoweredOn {
+		t.Error("panel left powered on")
+	}
+	if e.CDI != regs.CDIPowerOff {
+		t.Errorf("CDI = %02x, want %02x from the last power up", e.CDI, regs.CDIPowerOff)
+	}
+	checkViolations(t, e)
+}
+
+func TestPowerDownBusError(t *testing.T) {
+	t.Parallel()
+	_, d, bus := failingPanel(t, 0)
+	checkBusError(t, d.PowerDown(), il0373.IL0373_CDI)
+	// CDI, VCM_DC_SETTING and POWER_OFF are all tried
+	if bus.Calls < 3 {
+		t.Errorf("%d transfers tried, want one per command", bus.Calls)
+	}
+}
@@ -0,0 +1,141 @@
+package il0373
+
+import "time"
+
+// Type CleanPolicy decides when a refresh is preceded by a clean cycle, which
+// drives the whole panel black, red and then white to clear the ghosting
+// left behind by partial refreshes. Zero fields are disabled.
+type CleanPolicy struct {
+	// MaxPartial cleans once this many partial refreshes have run since the
+	// last clean
+	MaxPartial int
+	// MaxFull cleans once this many full refreshes have run since the last
+	// clean
+	MaxFull int
+	// MaxAge cleans once the last clean is older than this, for panels that
+	// sit on one image for days
+	MaxAge time.Duration
+}
+
+// The panel vendor recommends a full clear after 5 partial refreshes
+var DefaultCleanPolicy = CleanPolicy{MaxPartial: 5}
+
+// Type RefreshStats counts refreshes since the last clean cycle
+type RefreshStats struct {
+	Full    int
+	Partial int
+	// Since is when the last clean ran, or the first refresh if there's
+	// been none
+	Since time.Time
+	// Cleans is the total number of clean cycles run
+	Cleans int
+}
+
+// Func RefreshStats returns the refresh counters
+func (d *Device) RefreshStats() RefreshStats {
+	d.busMu.Lock()
+	defer d.busMu.Unlock()
+	return d.stats
+}
+
+// Func Clean runs a clean cycle now. The panel is left white, the
+// framebuffers are untouched.
+func (d *Device) Clean() error {
+	d.busMu.Lock()
+	defer d.busMu.Unlock()
+
+	if err := d.PowerUp(); err != nil {
+		return err
+	}
+	return d.clean()
+}
+
+func (d *Device) needsClean() bool {
+	p, s := d.CleanPolicy, d.stats
+	switch {
+	case p.MaxPartial > 0 && s.Partial >= p.MaxPartial:
+		return true
+	case p.MaxFull > 0 && s.Full >= p.MaxFull:
+		return true
+	case p.MaxAge > 0 && !s.Since.IsZero() && time.Since(s.Since) >= p.MaxAge:
+		return true
+	}
+	return false
+}
+
+// Func counted records a finished refresh
+func (d *Device) counted(partial bool) {
+	if partial {
+		d.stats.Partial++
+	} else {
+		d.stats.Full++
+	}
+	if d.stats.Since.IsZero() {
+		d.stats.Since = time.Now()
+	}
+}
+
+// Func clean drives the panel black, red and white, the panel must be
+// powered up
+func (d *Device) clean() error {
+	steps := [][2]byte{{0x00, 0xFF}, {0xFF, 0x00}, {0xFF, 0xFF}}
+	if d.Config.Monochrome || d.buffer2_size == 0 {
+		steps = [][2]byte{{0x00, 0xFF}, {0xFF, 0xFF}}
+	}
+	// The steps are in normal polarity, a 0 inks
+	var mask1, mask2 byte
+	if d.Config.DataPolarity&PolarityInvertBlack != 0 {
+		mask1 = 0xFF
+	}
+	if d.Config.DataPolarity&PolarityInvertRed != 0 {
+		mask2 = 0xFF
+	}
+	for _, step := range steps {
+		if err := d.fillRam(IL0373_DTM1, step[0]^mask1, d.buffer1_size); err != nil {
+			return err
+		}
+		if err := d.fillRam(IL0373_DTM2, step[1]^mask2, d.buffer2_size); err != nil {
+			return err
+		}
+		if err := d.Update(); err != nil {
+			return err
+		}
+	}
+	d.stats = RefreshStats{Since: time.Now(), Cleans: d.stats.Cleans + 1}
+	return nil
+}
+
+// Func fillRam writes size bytes of val with cmd
+func (d *Device) fillRam(cmd byte, val byte, size int) error {
+	if size == 0 {
+		return nil
+	}
+	chunk := d.ChunkSize
+	if chunk <= 0 {
+		chunk = DefaultChunkSize
+	}
+	if chunk > size {
+		chunk = size
+	}
+	buf := make([]byte, chunk)
+	for i := range buf {
+		buf[i] = val
+	}
+
+	if _, err := d.command(cmd, nil, false); err != nil {
+		return err
+	}
+	d.DC_PIN.High()
+	defer d.CS_PIN.High()
+	for size > 0 {
+		n := len(buf)
+		if n > size {
+			n = size
+		}
+		if err := d.writeData(buf[:n]); err != nil {
+			return err
+		}
+		size -= n
+	}
+	return nil
+}
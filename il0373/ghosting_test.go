@@ -0,0 +1,87 @@
+package il0373_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/davidadeleon/gophercon2022Badge/il0373"
+)
+
+// A clean cycle is three refreshes, black, red and white
+const cleanRefreshes = 3
+
+func TestCleanAfterMaxPartial(t *testing.T) {
+	t.Parallel()
+	e, d := newPanel(t)
+	d.CleanPolicy = il0373.CleanPolicy{MaxPartial: 3}
+	d.Fill(il0373.WHITE)
+	d.Pixel(10, 10, il0373.BLACK)
+
+	for i := 0; i < d.CleanPolicy.MaxPartial; i++ {
+		if err := d.DisplayRegion(8, 8, 8, 8); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if e.Refreshes != 0 || e.PartialRefreshes != 3 {
+		t.Fatalf("%d full and %d partial refreshes before the clean", e.Refreshes, e.PartialRefreshes)
+	}
+	if s := d.RefreshStats(); s.Partial != 3 || s.Cleans != 0 {
+		t.Fatalf("stats before the clean %+v", s)
+	}
+
+	// One more is due a clean, and then the whole panel is redrawn
+	if err := d.DisplayRegion(8, 8, 8, 8); err != nil {
+		t.Fatal(err)
+	}
+	checkViolations(t, e)
+	if e.Refreshes != cleanRefreshes+1 || e.PartialRefreshes != 3 {
+		t.Errorf("%d full and %d partial refreshes, want %d and 3", e.Refreshes, e.PartialRefreshes, cleanRefreshes+1)
+	}
+	if s := d.RefreshStats(); s.Partial != 0 || s.Full != 1 || s.Cleans != 1 {
+		t.Errorf("stats after the clean %+v", s)
+	}
+	checkPixel(t, e, 10, 10, black)
+	checkPixel(t, e, 100, 200, white)
+}
+
+func TestCleanAfterMaxFull(t *testing.T) {
+	t.Parallel()
+	e, d := newPanel(t)
+	d.CleanPolicy = il0373.CleanPolicy{MaxFull: 2}
+	for i := 0; i < 3; i++ {
+		if err := d.Display(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	checkViolations(t, e)
+	if e.Refreshes != 2+cleanRefreshes+1 {
+		t.Errorf("%d refreshes, want %d", e.Refreshes, 2+cleanRefreshes+1)
+	}
+	if s := d.RefreshStats(); s.Full != 1 || s.Cleans != 1 {
+		t.Errorf("stats %+v", s)
+	}
+}
+
+func TestCleanAfterMaxAge(t *testing.T) {
+	t.Parallel()
+	e, d := newPanel(t)
+	d.CleanPolicy = il0373.CleanPolicy{MaxAge: time.Second}
+	if err := d.Display(); err != nil {
+		t.Fatal(err)
+	}
+	if e.Refreshes != 1 {
+		t.Fatalf("cleaned before MaxAge, %d refreshes", e.Refreshes)
+	}
+
+	time.Sleep(d.CleanPolicy.MaxAge)
+	if err := d.Display(); err != nil {
+		t.Fatal(err)
+	}
+	checkViolations(t, e)
+	if e.Refreshes != 1+cleanRefreshes+1 {
+		t.Errorf("%d refreshes, want %d", e.Refreshes, 1+cleanRefreshes+1)
+	}
+	if s := d.RefreshStats(); s.Cleans != 1 || s.Full != 1 || time.Since(s.Since) >= d.CleanPolicy.MaxAge {
+		t.Errorf("stats %+v", s)
+	}
+}
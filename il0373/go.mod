@@ -3,5 +3,13 @@ module github.com/davidadeleon/gophercon2022Badge/il0373
 go 1.19
 
 require (
+	github.com/davidadeleon/gophercon2022Badge/epd v0.0.0
+	github.com/davidadeleon/gophercon2022Badge/framebuffer v0.0.0
 	tinygo.org/x/drivers v0.22.0
+	tinygo.org/x/tinydraw v0.3.0
+)
+
+replace (
+	github.com/davidadeleon/gophercon2022Badge/epd => ../epd
+	github.com/davidadeleon/gophercon2022Badge/framebuffer => ../framebuffer
 )
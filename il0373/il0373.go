@@ -4,39 +4,101 @@ import (
 	"bytes"
 	"encoding/binary"
 	"image/color"
-	"machine"
+	"sync"
 	"time"
 
+	"github.com/davidadeleon/gophercon2022Badge/epd"
 	"github.com/davidadeleon/gophercon2022Badge/framebuffer"
 	"tinygo.org/x/drivers"
 )
 
+// Refreshing a tri-color panel takes ~15s so leave plenty of headroom
+const DefaultBusyTimeout = 30 * time.Second
+
+// The panel keeps changing for a while after BUSY releases, Update waits this
+// long before returning
+const DefaultRefreshDelay = 15 * time.Second
+
+// Frame data is streamed in bulk transfers of this many bytes by default
+const DefaultChunkSize = 256
+
 const (
-	BLACK int = iota
-	WHITE
-	INVERSE
-	RED
-	DARK
-	LIGHT
+	BLACK   = epd.BLACK
+	WHITE   = epd.WHITE
+	INVERSE = epd.INVERSE
+	RED     = epd.RED
+	DARK    = epd.DARK
+	LIGHT   = epd.LIGHT
+)
+
+var (
+	_ epd.AsyncDevice   = (*Device)(nil)
+	_ drivers.Displayer = (*Device)(nil)
 )
 
 type Device struct {
 	SPI         drivers.SPI
-	CS_PIN      machine.Pin
-	DC_PIN      machine.Pin
-	SRAM_CS_PIN machine.Pin
-	RST_PIN     machine.Pin
-	BUSY_PIN    machine.Pin
-
-	Width        int
-	Height       int
-	Rotation     int
+	CS_PIN      epd.Pin
+	DC_PIN      epd.Pin
+	SRAM_CS_PIN epd.Pin
+	RST_PIN     epd.Pin
+	BUSY_PIN    epd.Pin
+
+	Width    int
+	Height   int
+	Rotation int
+
+	// Config holds the power, booster, frame rate and VCOM settings PowerUp
+	// writes, tune it for the panel before Display
+	Config PanelConfig
+
+	// TemperaturePolicy decides what happens when a refresh finds the panel
+	// outside OperatingRange. LUTs, if set, are uploaded instead of the OTP
+	// waveform when the temperature falls in their range. TemperatureFunc
+	// replaces the controller's own sensor.
+	TemperaturePolicy TemperaturePolicy
+	OperatingRange    TemperatureRange
+	LUTs              []TemperatureLUT
+	TemperatureFunc   func() (int32, error)
+	LastTemperature   int32
+	LastTemperatureAt time.Time
+
+	// Palette is what SetPixel quantizes colors to. Pixels with an alpha
+	// below AlphaThreshold are left untouched.
+	Palette        Palette
+	AlphaThreshold uint8
+
+	// CleanPolicy decides when refreshes are preceded by a clean cycle to
+	// clear ghosting, see RefreshStats for the counters
+	CleanPolicy CleanPolicy
+	stats       RefreshStats
+
 	buffer1_size int
 	buffer2_size int
 
 	buffer1 []byte
 	buffer2 []byte
 
+	// DoubleBuffered, set before Initialize, gives each plane a back buffer
+	// that drawing goes to and a front buffer that's uploaded, see Present.
+	// Otherwise front and back are the same memory. In SRAM the planes are
+	// found at backOffset and frontOffset.
+	DoubleBuffered bool
+	front1         []byte
+	front2         []byte
+	backOffset     int
+	frontOffset    int
+
+	// frontMu is held while the front buffers are uploaded so Present can't
+	// swap them mid frame
+	frontMu sync.Mutex
+
+	// SRAMSize is the capacity of the SRAM on SRAM_CS_PIN, if one is wired
+	// up the framebuffers live there instead of buffer1/buffer2
+	SRAMSize int
+	sram     *SRAM
+	sramBuf  []byte
+
 	framebuf1        *framebuffer.FrameBuffer
 	framebuf2        *framebuffer.FrameBuffer
 	blackFrameBuffer *framebuffer.FrameBuffer
@@ -44,59 +106,87 @@ type Device struct {
 	blackInverted    bool
 	colorInverted    bool
 
+	// BusyTimeout bounds how long BusyWait waits for the BUSY pin
+	BusyTimeout time.Duration
+
+	// RefreshDelay is how long Update waits after the refresh for the
+	// panel to settle
+	RefreshDelay time.Duration
+
+	// ChunkSize is the number of bytes sent per SPI transfer when uploading
+	// frame data, values <= 0 use DefaultChunkSize
+	ChunkSize int
+
 	spiBuf       []byte
 	singleByteTx bool
+	lastCmd      byte
+
+	// powerOffCDI is the CDI byte from the last good PowerUp, PowerDown
+	// falls back to it when Config no longer encodes
+	powerOffCDI byte
 
 	_buf []byte
+
+	// busMu serialises every transaction on the panel so a synchronous
+	// Display can't interleave with one running in the background
+	busMu sync.Mutex
+	async asyncState
 }
 
-func New(width, height int, bus drivers.SPI, csPin, dcPin, sramCSPin, rstPin, busyPin machine.Pin) *Device {
+func New(width, height int, bus drivers.SPI, csPin, dcPin, sramCSPin, rstPin, busyPin epd.Pin) *Device {
 	return &Device{
-		Width:       width,
-		Height:      height,
-		SPI:         bus,
-		CS_PIN:      csPin,
-		DC_PIN:      dcPin,
-		SRAM_CS_PIN: sramCSPin,
-		RST_PIN:     rstPin,
-		BUSY_PIN:    busyPin,
+		Width:          width,
+		Height:         height,
+		SPI:            bus,
+		CS_PIN:         csPin,
+		DC_PIN:         dcPin,
+		SRAM_CS_PIN:    sramCSPin,
+		RST_PIN:        rstPin,
+		BUSY_PIN:       busyPin,
+		Config:         DefaultPanelConfig(),
+		OperatingRange: DefaultOperatingRange,
+		Palette:        DefaultPalette(),
+		CleanPolicy:    DefaultCleanPolicy,
+		BusyTimeout:    DefaultBusyTimeout,
+		RefreshDelay:   DefaultRefreshDelay,
+		ChunkSize:      DefaultChunkSize,
+		SRAMSize:       DefaultSRAMSize,
+		powerOffCDI:    DefaultPanelConfig().cdiPowerOff(),
 	}
 }
 
-func (d *Device) Initialize() {
+func (d *Device) Initialize() error {
+	if d.Width <= 0 || d.Height <= 0 || (d.Width*d.Height)%8 != 0 {
+		return ErrBadDimensions
+	}
+
 	// Setup reset pin if provided
-	if d.RST_PIN != machine.NoPin {
-		d.RST_PIN.Configure(machine.PinConfig{Mode: machine.PinOutput})
+	if epd.Connected(d.RST_PIN) {
+		epd.Configure(d.RST_PIN, epd.PinOutput)
 	}
 
 	// Setup busy pin if provided
-	if d.BUSY_PIN != machine.NoPin {
-		d.BUSY_PIN.Configure(machine.PinConfig{Mode: machine.PinInput})
+	if epd.Connected(d.BUSY_PIN) {
+		epd.Configure(d.BUSY_PIN, epd.PinInput)
 	}
 
 	// Setup DC Pin (required)
-	d.DC_PIN.Configure(machine.PinConfig{Mode: machine.PinOutput})
+	epd.Configure(d.DC_PIN, epd.PinOutput)
 	d.DC_PIN.Low()
 
 	// Setup CS Pin (required)
-	d.CS_PIN.Configure(machine.PinConfig{Mode: machine.PinOutput})
+	epd.Configure(d.CS_PIN, epd.PinOutput)
 	d.CS_PIN.High()
 
 	d.spiBuf = make([]byte, 1)
 	d.singleByteTx = false
 
-	// TODO: Implement SRAM
-
 	d._buf = make([]byte, 3)
 
 	d.buffer1_size = int(d.Width * d.Height / 8)
 	d.buffer2_size = d.buffer1_size
 
-	d.buffer1 = make([]byte, (d.Width*d.Height)/8)
-	d.buffer2 = make([]byte, (d.Width*d.Height)/8)
-
 	d.framebuf1 = &framebuffer.FrameBuffer{
-		Buf:      &d.buffer1,
 		Width:    d.Width,
 		Height:   d.Height,
 		Stride:   d.Width, // No Calc for this so default to width
@@ -104,86 +194,188 @@ func (d *Device) Initialize() {
 	}
 
 	d.framebuf2 = &framebuffer.FrameBuffer{
-		Buf:      &d.buffer2,
 		Width:    d.Width,
 		Height:   d.Height,
 		Stride:   d.Width, // No Calc for this so default to width
 		Rotation: d.Rotation,
 	}
-	d.SetBlackBuffer(0, true)
-	d.SetColorBuffer(1, true)
+
+	if epd.Connected(d.SRAM_CS_PIN) {
+		// Keep both planes in the external SRAM, one after the other
+		planes := d.buffer1_size + d.buffer2_size
+		d.backOffset, d.frontOffset = 0, 0
+		if d.DoubleBuffered {
+			// The front planes follow the back ones
+			d.frontOffset = planes
+			planes *= 2
+		}
+		if planes > d.SRAMSize {
+			return ErrBadDimensions
+		}
+		d.sram = NewSRAM(d.SPI, d.SRAM_CS_PIN, d.SRAMSize)
+		d.sram.lock = &d.busMu
+		if err := d.sram.Configure(); err != nil {
+			return err
+		}
+		d.sramBuf = make([]byte, DefaultChunkSize)
+		d.framebuf1.Store = d.sram.Region(0, d.buffer1_size)
+		d.framebuf2.Store = d.sram.Region(d.buffer1_size, d.buffer2_size)
+	} else {
+		d.buffer1 = make([]byte, d.buffer1_size)
+		d.buffer2 = make([]byte, d.buffer2_size)
+		d.framebuf1.Buf = &d.buffer1
+		d.framebuf2.Buf = &d.buffer2
+		d.front1, d.front2 = d.buffer1, d.buffer2
+		if d.DoubleBuffered {
+			d.front1 = make([]byte, d.buffer1_size)
+			d.front2 = make([]byte, d.buffer2_size)
+		}
+	}
+	// The buffers hold a 0 for an inked pixel unless Config inverts the plane
+	if err := d.SetBlackBuffer(0, d.Config.DataPolarity&PolarityInvertBlack == 0); err != nil {
+		return err
+	}
+	if err := d.SetColorBuffer(1, d.Config.DataPolarity&PolarityInvertRed == 0); err != nil {
+		return err
+	}
 	d.HardwareReset()
+	return nil
 }
 
 //=============================================================\\
 //                   il0373 Specific Functions                 \\
 //=============================================================\\
 
-func (d *Device) Begin(reset bool) {
+func (d *Device) Begin(reset bool) error {
 	if reset {
 		d.HardwareReset()
 	}
-	d.PowerDown()
+	return d.PowerDown()
 }
 
-func (d *Device) BusyWait() {
-	if d.BUSY_PIN != machine.NoPin {
+func (d *Device) BusyWait() error {
+	if epd.Connected(d.BUSY_PIN) {
+		start := time.Now()
 		for d.BUSY_PIN.Get() {
+			if d.BusyTimeout > 0 && time.Since(start) > d.BusyTimeout {
+				return ErrBusyTimeout
+			}
 			time.Sleep(10 * time.Millisecond)
 		}
-		return
+		return nil
 	}
 	time.Sleep(500 * time.Millisecond)
+	return nil
 }
 
 func (d *Device) PowerUp() error {
+	regs, err := d.Config.Encode(d.Width, d.Height)
+	if err != nil {
+		return err
+	}
+	d.powerOffCDI = regs.CDIPowerOff
+
 	d.HardwareReset()
-	d.BusyWait()
+	if err := d.BusyWait(); err != nil {
+		return err
+	}
 
-	d.command(IL0373_POWER_SETTING, []byte{0x03, 0x00, 0x2B, 0x2B, 0x09}, true)
-	d.command(IL0373_BOOSTER_SOFT_START, []byte{0x17, 0x17, 0x17}, true)
-	d.command(IL0373_POWER_ON, nil, true)
+	if _, err := d.command(IL0373_POWER_SETTING, regs.PowerSetting[:], true); err != nil {
+		return err
+	}
+	if _, err := d.command(IL0373_BOOSTER_SOFT_START, regs.BoosterSoftStart[:], true); err != nil {
+		return err
+	}
+	if _, err := d.command(IL0373_POWER_ON, nil, true); err != nil {
+		return err
+	}
 
-	d.BusyWait()
+	if err := d.BusyWait(); err != nil {
+		return err
+	}
 	time.Sleep(200 * time.Millisecond)
 
-	d.command(IL0373_PANEL_SETTING, []byte{0xCF}, true)
-	d.command(IL0373_CDI, []byte{0x37}, true)
-	d.command(IL0373_PLL, []byte{0x29}, true)
+	lut, err := d.checkTemperature()
+	if err != nil {
+		d.command(IL0373_POWER_OFF, nil, true)
+		return err
+	}
+	if lut != nil {
+		// Run the uploaded waveform instead of the OTP one
+		regs.PanelSetting |= 1 << 5
+	}
 
-	_b1 := byte(d.Width & 0xFF)
-	_b2 := byte((d.Height >> 8) & 0xFF)
-	_b3 := byte(d.Height & 0xFF)
-	d.command(IL0373_RESOLUTION, []byte{_b1, _b2, _b3}, true)
-	d.command(IL0373_VCM_DC_SETTING, []byte{0x0A}, true)
+	cmds := []cmd{
+		{IL0373_PANEL_SETTING, []byte{regs.PanelSetting}},
+		{IL0373_CDI, []byte{regs.CDI}},
+		{IL0373_PLL, []byte{regs.PLL}},
+	}
+	if regs.SendResolution {
+		cmds = append(cmds, cmd{IL0373_RESOLUTION, regs.Resolution[:]})
+	}
+	cmds = append(cmds, cmd{IL0373_VCM_DC_SETTING, []byte{regs.VCMDC}})
+	if err := d.commands(cmds); err != nil {
+		return err
+	}
+	if lut != nil {
+		if err := d.writeLUT(lut); err != nil {
+			return err
+		}
+	}
 	time.Sleep(20 * time.Millisecond)
 
 	return nil
 }
 
-func (d *Device) PowerDown() {
-	d.command(IL0373_CDI, []byte{0x17}, true)
-	d.command(IL0373_VCM_DC_SETTING, []byte{0x00}, true)
-	d.command(IL0373_POWER_OFF, nil, true)
+// Func PowerDown floats the border and turns the supplies off. POWER_OFF is
+// always sent: if Config doesn't encode, the CDI from the last good PowerUp is
+// used, and if a command fails the rest are still tried. The first error is
+// returned.
+func (d *Device) PowerDown() error {
+	d.busMu.Lock()
+	defer d.busMu.Unlock()
+
+	cdi := d.powerOffCDI
+	regs, err := d.Config.Encode(d.Width, d.Height)
+	if err == nil {
+		cdi = regs.CDIPowerOff
+	}
+	for _, c := range []cmd{
+		{IL0373_CDI, []byte{cdi}},
+		{IL0373_VCM_DC_SETTING, []byte{0x00}},
+		{IL0373_POWER_OFF, nil},
+	} {
+		if _, cerr := d.command(c.cmd, c.data, true); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
 }
 
-func (d *Device) Update() {
-	d.command(IL0373_DISPLAY_REFRESH, nil, true)
+func (d *Device) Update() error {
+	if _, err := d.command(IL0373_DISPLAY_REFRESH, nil, true); err != nil {
+		return err
+	}
 	time.Sleep(100 * time.Millisecond)
-	d.BusyWait()
-	println("[IL0373] device waiting...")
-	time.Sleep(15 * time.Second)
-	println("[IL0373] done waiting!")
+	if err := d.BusyWait(); err != nil {
+		return err
+	}
+	if d.RefreshDelay > 0 {
+		println("[IL0373] device waiting...")
+		time.Sleep(d.RefreshDelay)
+		println("[IL0373] done waiting!")
+	}
+	return nil
 }
 
-func (d *Device) WriteRam(index int) byte {
+func (d *Device) WriteRam(index int) (byte, error) {
 	switch index {
 	case 0:
 		return d.command(IL0373_DTM1, nil, false)
 	case 1:
 		return d.command(IL0373_DTM2, nil, false)
 	default:
-		return 0
+		return 0, ErrInvalidState
 	}
 }
 
@@ -191,39 +383,214 @@ func (d *Device) WriteRam(index int) byte {
 //                     Generic EPD Functions                   \\
 //=============================================================\\
 
+// Func Display uploads both framebuffers and refreshes the panel, blocking
+// until the refresh has finished. See DisplayAsync for a non-blocking version.
+// When DoubleBuffered the front buffers are uploaded, call Present first.
 func (d *Device) Display() error {
-	d.PowerUp()
+	return d.display(nil, nil)
+}
 
-	d.WriteRam(0)
+// Func display uploads the given planes, or the front buffers if they're nil
+func (d *Device) display(buffer1, buffer2 []byte) error {
+	if !d.ready() {
+		return ErrInvalidState
+	}
+	if d.sram != nil {
+		if err := d.sram.Err(); err != nil {
+			return err
+		}
+	}
+
+	d.busMu.Lock()
+	defer d.busMu.Unlock()
+	return d.fullRefresh(buffer1, buffer2)
+}
+
+// Func fullRefresh powers up, runs a clean cycle if one is due, uploads both
+// planes, or the front buffers if they're nil, and refreshes. busMu must be
+// held.
+func (d *Device) fullRefresh(buffer1, buffer2 []byte) error {
+	if err := d.PowerUp(); err != nil {
+		return err
+	}
+	if d.needsClean() {
+		if err := d.clean(); err != nil {
+			return err
+		}
+	}
+	if err := d.uploadFrame(buffer1, buffer2); err != nil {
+		return err
+	}
+
+	if err := d.Update(); err != nil {
+		return err
+	}
+	d.counted(false)
+	return nil
+}
+
+// Func uploadFrame writes both planes to the panel RAM, the front buffers if
+// buffer1 is nil. busMu must be held.
+func (d *Device) uploadFrame(buffer1, buffer2 []byte) error {
+	offset := 0
+	if buffer1 == nil {
+		// Present waits for the upload, but not for the refresh after it
+		d.frontMu.Lock()
+		defer d.frontMu.Unlock()
+		buffer1, buffer2, offset = d.front1, d.front2, d.frontOffset
+	}
+
+	if _, err := d.WriteRam(0); err != nil {
+		return err
+	}
 	d.DC_PIN.High()
 
-	for i := 0; i < d.buffer1_size; i++ {
-		d.SPITransfer(d.buffer1[i])
+	if err := d.writePlane(buffer1, offset, d.buffer1_size); err != nil {
+		d.CS_PIN.High()
+		return err
 	}
 
 	d.CS_PIN.High()
 	time.Sleep(20 * time.Millisecond)
 
 	if d.buffer2_size != 0 {
-		d.WriteRam(1)
+		if _, err := d.WriteRam(1); err != nil {
+			return err
+		}
 
 		time.Sleep(10 * time.Millisecond)
 		d.DC_PIN.High()
 
-		for i := 0; i < d.buffer2_size; i++ {
-			d.SPITransfer(d.buffer2[i])
+		if err := d.writePlane(buffer2, offset+d.buffer1_size, d.buffer2_size); err != nil {
+			d.CS_PIN.High()
+			return err
 		}
 
 		d.CS_PIN.High()
 	}
+	return nil
+}
 
-	d.Update()
+// Func ready reports whether Initialize has set up the framebuffers. It
+// doesn't look at the buffers themselves as Present swaps them.
+func (d *Device) ready() bool {
+	return d.sram != nil || d.framebuf1 != nil && d.framebuf1.Buf != nil
+}
+
+// Func Init satisfies epd.Device
+func (d *Device) Init() error {
+	return d.Initialize()
+}
+
+// Func DisplayRegion uploads and refreshes a window of the panel using the
+// partial data transmission commands. Coordinates are in panel (unrotated)
+// space, x and width are rounded out to whole bytes.
+func (d *Device) DisplayRegion(x, y, width, height int) error {
+	x, y, width, height, err := epd.AlignRegion(x, y, width, height, d.Width, d.Height)
+	if err != nil {
+		return err
+	}
+	if !d.ready() {
+		return ErrInvalidState
+	}
+
+	d.busMu.Lock()
+	defer d.busMu.Unlock()
+
+	// A clean cycle wipes the whole panel so redraw all of it
+	if d.needsClean() {
+		return d.fullRefresh(nil, nil)
+	}
+
+	if err := d.PowerUp(); err != nil {
+		return err
+	}
+
+	// X and W must be byte aligned, Y and L are sent as 9 bit values
+	window := []byte{byte(x), byte(y >> 8), byte(y), byte(width), byte(height >> 8), byte(height)}
+	if err := d.uploadWindow(window, x, y, width, height); err != nil {
+		return err
+	}
+
+	if _, err := d.command(IL0373_PDRF, window, true); err != nil {
+		return err
+	}
+	time.Sleep(100 * time.Millisecond)
+	if err := d.BusyWait(); err != nil {
+		return err
+	}
+	d.counted(true)
+	return nil
+}
+
+// Func uploadWindow writes a window of both front planes to the panel RAM.
+// busMu must be held.
+func (d *Device) uploadWindow(window []byte, x, y, width, height int) error {
+	// Present waits for the upload, but not for the refresh after it
+	d.frontMu.Lock()
+	defer d.frontMu.Unlock()
+
+	planes := []struct {
+		cmd    byte
+		buf    []byte
+		offset int
+	}{
+		{IL0373_PDTM1, d.front1, d.frontOffset},
+		{IL0373_PDTM2, d.front2, d.frontOffset + d.buffer1_size},
+	}
+	stride := d.Width / 8
+	row := make([]byte, width/8)
+	for _, p := range planes {
+		if _, err := d.command(p.cmd, window, false); err != nil {
+			return err
+		}
+		for r := y; r < y+height; r++ {
+			start := r*stride + x/8
+			if p.buf != nil {
+				copy(row, p.buf[start:])
+			} else {
+				// The SRAM shares the bus, deselect the panel to read it
+				d.CS_PIN.High()
+				if err := d.sram.read(p.offset+start, row); err != nil {
+					return err
+				}
+			}
+			d.DC_PIN.High()
+			d.CS_PIN.Low()
+			if err := d.writeData(row); err != nil {
+				d.CS_PIN.High()
+				return err
+			}
+		}
+		d.CS_PIN.High()
+	}
 	return nil
 }
 
+// Func Sleep powers the panel down and puts the controller in deep sleep.
+// Without a reset pin it's only powered down, deep sleep is left by a reset.
+func (d *Device) Sleep() error {
+	if err := d.PowerDown(); err != nil {
+		return err
+	}
+	if !epd.Connected(d.RST_PIN) {
+		return nil
+	}
+
+	d.busMu.Lock()
+	defer d.busMu.Unlock()
+	_, err := d.command(IL0373_DEEP_SLEEP, []byte{0xA5}, true)
+	return err
+}
+
+// Func Buffers returns the black and color framebuffers
+func (d *Device) Buffers() (black, color *framebuffer.FrameBuffer) {
+	return d.blackFrameBuffer, d.colorFrameBuffer
+}
+
 func (d *Device) HardwareReset() {
 	// If we assigned a reset pin, do hardware reset
-	if d.RST_PIN != machine.NoPin {
+	if epd.Connected(d.RST_PIN) {
 		d.RST_PIN.Low()
 		time.Sleep(100 * time.Millisecond)
 		d.RST_PIN.High()
@@ -231,69 +598,278 @@ func (d *Device) HardwareReset() {
 	}
 }
 
-func (d *Device) command(cmd byte, data []byte, end bool) byte {
+// Type cmd is a single command and its data, used to send a sequence of
+// commands that each end the transaction
+type cmd struct {
+	cmd  byte
+	data []byte
+}
+
+func (d *Device) commands(cmds []cmd) error {
+	for _, c := range cmds {
+		if _, err := d.command(c.cmd, c.data, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *Device) command(cmd byte, data []byte, end bool) (byte, error) {
+	d.lastCmd = cmd
 	d.CS_PIN.High()
 	d.DC_PIN.Low()
 	d.CS_PIN.Low()
 
-	ret := d.SPITransfer(cmd)
+	ret, err := d.SPITransfer(cmd)
+	if err != nil {
+		d.CS_PIN.High()
+		return 0, err
+	}
 
 	if data != nil {
 		d.DC_PIN.High()
 		for i := 0; i < len(data); i++ {
-			d.SPITransfer(data[i])
+			if _, err := d.SPITransfer(data[i]); err != nil {
+				d.CS_PIN.High()
+				return 0, err
+			}
 		}
 	}
 	if end {
 		d.CS_PIN.High()
 	}
-	return ret
+	return ret, nil
+}
+
+// Func writeData streams data to the panel in ChunkSize transfers. DC and CS
+// must already be set up for a data phase.
+func (d *Device) writeData(data []byte) error {
+	chunk := d.ChunkSize
+	if chunk <= 0 {
+		chunk = DefaultChunkSize
+	}
+	for len(data) > 0 {
+		n := chunk
+		if n > len(data) {
+			n = len(data)
+		}
+		if err := d.SPI.Tx(data[:n], nil); err != nil {
+			return &BusError{Cmd: d.lastCmd, Err: err}
+		}
+		data = data[n:]
+	}
+	return nil
+}
+
+// Func writePlane sends size bytes of a plane, from buf if it's in RAM or from
+// the SRAM at offset otherwise. The panel is deselected while the SRAM is read
+// as both share the bus.
+func (d *Device) writePlane(buf []byte, offset, size int) error {
+	if buf != nil {
+		return d.writeData(buf[:size])
+	}
+	for size > 0 {
+		n := len(d.sramBuf)
+		if n > size {
+			n = size
+		}
+		d.CS_PIN.High()
+		if err := d.sram.read(offset, d.sramBuf[:n]); err != nil {
+			return err
+		}
+		d.DC_PIN.High()
+		d.CS_PIN.Low()
+		if err := d.writeData(d.sramBuf[:n]); err != nil {
+			return err
+		}
+		offset += n
+		size -= n
+	}
+	return nil
 }
 
-func (d *Device) SPITransfer(databyte byte) byte {
+func (d *Device) SPITransfer(databyte byte) (byte, error) {
+	if d.spiBuf == nil {
+		return 0, ErrInvalidState
+	}
 	d.spiBuf[0] = databyte
-	err := d.SPI.Tx(d.spiBuf, d.spiBuf)
-	if err != nil {
-		println(err)
+	if err := d.SPI.Tx(d.spiBuf, d.spiBuf); err != nil {
+		return 0, &BusError{Cmd: d.lastCmd, Err: err}
 	}
-	return d.spiBuf[0]
+	return d.spiBuf[0], nil
 }
 
-func (d *Device) SetBlackBuffer(index int, inverted bool) {
+func (d *Device) SetBlackBuffer(index int, inverted bool) error {
 	switch index {
 	case 0:
 		d.blackFrameBuffer = d.framebuf1
 	case 1:
 		d.blackFrameBuffer = d.framebuf2
 	default:
-		println("Buffer index must be 0 or 1")
+		return ErrInvalidState
 	}
 	d.blackInverted = inverted
+	return nil
 }
 
-func (d *Device) SetColorBuffer(index int, inverted bool) {
+func (d *Device) SetColorBuffer(index int, inverted bool) error {
 	switch index {
 	case 0:
 		d.colorFrameBuffer = d.framebuf1
 	case 1:
 		d.colorFrameBuffer = d.framebuf2
 	default:
-		println("Buffer index must be 0 or 1")
+		return ErrInvalidState
 	}
 	d.colorInverted = inverted
+	return nil
 }
 
-func (d *Device) SetRotation(val int) {
-	d.blackFrameBuffer.SetRotation(val)
-	d.colorFrameBuffer.SetRotation(val)
+func (d *Device) SetRotation(val int) error {
+	if d.blackFrameBuffer == nil || d.colorFrameBuffer == nil {
+		return ErrInvalidState
+	}
+	if err := d.blackFrameBuffer.SetRotation(val); err != nil {
+		return err
+	}
+	if err := d.colorFrameBuffer.SetRotation(val); err != nil {
+		return err
+	}
+	d.Rotation = val
+	return nil
 }
 
-func (d *Device) Clear() {
+// Func SetBorder sets the border color driven on every refresh from now on
+func (d *Device) SetBorder(border BorderColor) error {
+	d.busMu.Lock()
+	defer d.busMu.Unlock()
+
+	config := d.Config
+	config.Border = border
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	d.Config = config
+	return nil
+}
+
+// Func SetDataPolarity sets which bit value inks a pixel in each plane. The
+// framebuffers, and any frame queued by DisplayAsync, are inverted to match so
+// what's been drawn is kept.
+func (d *Device) SetDataPolarity(polarity DataPolarity) error {
+	d.busMu.Lock()
+	defer d.busMu.Unlock()
+
+	config := d.Config
+	config.DataPolarity = polarity
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	flipped := d.Config.DataPolarity ^ polarity
+	d.Config = config
+	if d.blackFrameBuffer == nil {
+		// Initialize takes the buffer polarity from Config
+		return nil
+	}
+
+	// DisplayAsync snapshots the buffers under this lock
+	d.async.mu.Lock()
+	defer d.async.mu.Unlock()
+
+	if flipped&PolarityInvertBlack != 0 {
+		d.blackInverted = !d.blackInverted
+		if err := d.invertPlane(d.planeIndex(d.blackFrameBuffer)); err != nil {
+			return err
+		}
+	}
+	if flipped&PolarityInvertRed != 0 {
+		d.colorInverted = !d.colorInverted
+		if d.colorFrameBuffer != d.blackFrameBuffer {
+			if err := d.invertPlane(d.planeIndex(d.colorFrameBuffer)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Func planeIndex returns which plane, 0 for DTM1 and 1 for DTM2, fb draws to
+func (d *Device) planeIndex(fb *framebuffer.FrameBuffer) int {
+	if fb == d.framebuf2 {
+		return 1
+	}
+	return 0
+}
+
+// Func invertPlane flips every bit of a plane in the back and front buffers
+// and the queued async frame. busMu and async.mu must be held.
+func (d *Device) invertPlane(index int) error {
+	if d.sram != nil {
+		offset, size := 0, d.buffer1_size
+		if index == 1 {
+			offset, size = d.buffer1_size, d.buffer2_size
+		}
+		if err := d.invertSRAM(d.backOffset+offset, size); err != nil {
+			return err
+		}
+		if d.DoubleBuffered {
+			return d.invertSRAM(d.frontOffset+offset, size)
+		}
+		return nil
+	}
+
+	back, front, pending := d.buffer1, d.front1, d.async.pending1
+	if index == 1 {
+		back, front, pending = d.buffer2, d.front2, d.async.pending2
+	}
+	invert(back)
+	if d.DoubleBuffered {
+		invert(front)
+	}
+	if d.async.hasPending {
+		invert(pending)
+	}
+	return nil
+}
+
+// Func invertSRAM flips size bytes of SRAM from offset, a chunk at a time
+// through MCU RAM
+func (d *Device) invertSRAM(offset, size int) error {
+	for done := 0; done < size; done += len(d.sramBuf) {
+		chunk := d.sramBuf
+		if done+len(chunk) > size {
+			chunk = chunk[:size-done]
+		}
+		if err := d.sram.read(offset+done, chunk); err != nil {
+			return err
+		}
+		invert(chunk)
+		if err := d.sram.write(offset+done, chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func invert(buf []byte) {
+	for i := range buf {
+		buf[i] = ^buf[i]
+	}
+}
+
+func (d *Device) Clear() error {
+	if d.blackFrameBuffer == nil || d.colorFrameBuffer == nil {
+		return ErrInvalidState
+	}
 	d.blackFrameBuffer.Clear()
 	d.colorFrameBuffer.Clear()
+	return nil
 }
 
-func (d *Device) Fill(color int) {
+func (d *Device) Fill(color int) error {
+	if d.blackFrameBuffer == nil || d.colorFrameBuffer == nil {
+		return ErrInvalidState
+	}
 	isRed := color == RED
 	redInverted := 0
 	if isRed != d.colorInverted {
@@ -311,23 +887,31 @@ func (d *Device) Fill(color int) {
 	black_fill := uint8(blackInverted) * 0xFF
 	d.blackFrameBuffer.Fill(black_fill)
 	d.colorFrameBuffer.Fill(red_fill)
+	return nil
 }
 
-func (d *Device) FillRect(x, y, width, height int, color int) {
+func (d *Device) FillRect(x, y, width, height int, color int) error {
+	if d.blackFrameBuffer == nil || d.colorFrameBuffer == nil {
+		return ErrInvalidState
+	}
 	// Monochrome
 	if d.blackFrameBuffer == d.colorFrameBuffer {
 		d.blackFrameBuffer.FillRect(x, y, width, height, byte(color))
-		return
+		return nil
 	}
 	d.blackFrameBuffer.FillRect(x, y, width, height, byte(color))
 	d.colorFrameBuffer.FillRect(x, y, width, height, byte(color))
+	return nil
 }
 
-func (d *Device) Pixel(x, y int, color int) {
+func (d *Device) Pixel(x, y int, color int) error {
+	if d.blackFrameBuffer == nil || d.colorFrameBuffer == nil {
+		return ErrInvalidState
+	}
 	// Monochrome
 	if d.blackFrameBuffer == d.colorFrameBuffer {
 		d.blackFrameBuffer.Pixel(x, y, color)
-		return
+		return nil
 	}
 
 	if (color == BLACK) != d.blackInverted {
@@ -341,32 +925,66 @@ func (d *Device) Pixel(x, y int, color int) {
 	} else {
 		d.colorFrameBuffer.Pixel(x, y, 0)
 	}
+	return nil
 }
 
-// Satisfy Displayer Interface to use TinyFonts
+// drivers.Displayer, so tinyfont, tinydraw and tinyterm can draw on the panel.
+// Coordinates are in the current rotation and colors are natural, black is
+// RGBA{0, 0, 0, 255}.
 
-// Func Size returns size of display
+// Func Size returns size of display in the current rotation
 func (d *Device) Size() (int16, int16) {
+	if d.Rotation&1 == 1 {
+		return int16(d.Height), int16(d.Width)
+	}
 	return int16(d.Width), int16(d.Height)
 }
 
-// SetPixel modifies the internal buffer.
+// SetPixel modifies the internal buffer, drawing the Palette color nearest
+// to c. It does nothing before Initialize.
 func (d *Device) SetPixel(x, y int16, c color.RGBA) {
-	// RED
-	if c.R == 255 && c.G == 0 && c.B == 0 {
-		d.Pixel(int(x), int(y), RED)
+	if c.A < d.AlphaThreshold || d.blackFrameBuffer == nil {
 		return
 	}
-	// White
-	if c.R == 0 && c.G == 0 && c.B == 0 {
-		d.Pixel(int(x), int(y), WHITE)
-		return
+	d.Pixel(int(x), int(y), d.Palette.Nearest(c))
+}
+
+// Func FillRectangle fills a rectangle with the Palette color nearest to c,
+// clipped to the display
+func (d *Device) FillRectangle(x, y, width, height int16, c color.RGBA) error {
+	if d.blackFrameBuffer == nil {
+		return ErrInvalidState
 	}
-	// Black
-	if c.R == 255 && c.G == 255 && c.B == 255 {
-		d.Pixel(int(x), int(y), BLACK)
-		return
+	if width <= 0 || height <= 0 {
+		return ErrBadDimensions
+	}
+	if c.A < d.AlphaThreshold {
+		return nil
+	}
+	// Clip first so a rectangle mostly off the panel costs what it draws
+	w, h := d.Size()
+	x0, y0 := int(x), int(y)
+	x1, y1 := x0+int(width), y0+int(height)
+	if x0 < 0 {
+		x0 = 0
+	}
+	if y0 < 0 {
+		y0 = 0
+	}
+	if x1 > int(w) {
+		x1 = int(w)
+	}
+	if y1 > int(h) {
+		y1 = int(h)
 	}
+
+	ink := d.Palette.Nearest(c)
+	for py := y0; py < y1; py++ {
+		for px := x0; px < x1; px++ {
+			d.Pixel(px, py, ink)
+		}
+	}
+	return nil
 }
 
 func WriteBuf(buf *bytes.Buffer, vars ...interface{}) error {
@@ -378,25 +996,3 @@ func WriteBuf(buf *bytes.Buffer, vars ...interface{}) error {
 	}
 	return nil
 }
-
-func (d *Device) DisplayImage(imageWidth, imageHeight int, image [][]byte) {
-	width := d.Width
-	height := d.Height
-	if d.Rotation != 0 {
-		width, height = height, width
-	}
-	if imageWidth != width || imageHeight != height {
-		println("error displaying image: image must be same dimensions as display")
-		return
-	}
-
-	// Clear Buffer
-	d.Fill(1)
-
-	for y := 0; y < height; y++ {
-		for x := 0; x < width; x++ {
-			d.blackFrameBuffer.Pixel(x, y, int(image[x][y]))
-		}
-	}
-
-}
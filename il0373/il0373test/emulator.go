@@ -0,0 +1,471 @@
+package il0373test
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"time"
+
+	"github.com/davidadeleon/gophercon2022Badge/epd"
+	"github.com/davidadeleon/gophercon2022Badge/il0373"
+)
+
+// Type Pin is a fake GPIO. OnChange, if set, is called on every level change
+// the driver makes.
+type Pin struct {
+	Level      bool
+	Mode       epd.PinMode
+	Configured bool
+	OnChange   func(level bool)
+}
+
+func (p *Pin) ConfigureMode(mode epd.PinMode) {
+	p.Mode = mode
+	p.Configured = true
+}
+
+func (p *Pin) High() {
+	p.Set(true)
+}
+
+func (p *Pin) Low() {
+	p.Set(false)
+}
+
+func (p *Pin) Set(level bool) {
+	changed := p.Level != level
+	p.Level = level
+	if changed && p.OnChange != nil {
+		p.OnChange(level)
+	}
+}
+
+func (p *Pin) Get() bool {
+	return p.Level
+}
+
+// Type Emulator is a host-side IL0373. It's the SPI bus and CS/DC/RST/BUSY
+// pins for an il0373.Device and interprets the command/data stream the way
+// the controller does: register writes, DTM1/DTM2 into panel RAM, full and
+// partial refreshes, power and deep sleep. Anything the real controller would
+// choke on is recorded in Violations.
+//
+// Like the real part, RAM data keeps streaming into the current DTM or PDTM
+// command across CS pulses until the next command byte, other commands take
+// effect when CS rises.
+type Emulator struct {
+	Width  int
+	Height int
+
+	CS   *Pin
+	DC   *Pin
+	RST  *Pin
+	BUSY *Pin
+
+	// SRAM, set by AttachSRAM, shares the bus. Transfers go to it while
+	// SRAMCS is low.
+	SRAM   *FakeSRAM
+	SRAMCS *Pin
+
+	// Controller RAM written by DTM1/DTM2 and what the panel shows, which
+	// only changes on a refresh. The panel planes are latched through the
+	// CDI's DDX bits, so they always hold a 0 for an inked pixel, and red is
+	// dropped in black/white mode.
+	RAM1   []byte
+	RAM2   []byte
+	Panel1 []byte
+	Panel2 []byte
+
+	// Register state
+	PanelSetting     []byte
+	PowerSetting     []byte
+	BoosterSoftStart []byte
+	PLL              byte
+	CDI              byte
+	VCMDC            byte
+	ResWidth         int
+	ResHeight        int
+	TSE              byte
+	// LUTs uploaded to the waveform registers, by command
+	LUTs map[byte][]byte
+
+	// Temperature is what the internal sensor reads, in milli-degrees Celsius
+	Temperature int32
+
+	PoweredOn        bool
+	Asleep           bool
+	Refreshes        int
+	PartialRefreshes int
+
+	Violations []error
+
+	cmd    byte
+	inCmd  bool
+	args   []byte
+	ramPtr int
+}
+
+// Func NewEmulator returns an emulated width x height panel, powered off and
+// showing white
+func NewEmulator(width, height int) *Emulator {
+	size := width * height / 8
+	e := &Emulator{
+		Width:  width,
+		Height: height,
+		CS:     &Pin{Level: true},
+		DC:     &Pin{},
+		RST:    &Pin{Level: true},
+		BUSY:   &Pin{},
+		RAM1:   make([]byte, size),
+		RAM2:   make([]byte, size),
+		Panel1: make([]byte, size),
+		Panel2: make([]byte, size),
+		LUTs:   make(map[byte][]byte),
+		// Room temperature
+		Temperature: 25000,
+	}
+	for i := range e.Panel1 {
+		e.RAM1[i], e.RAM2[i] = 0xFF, 0xFF
+		e.Panel1[i], e.Panel2[i] = 0xFF, 0xFF
+	}
+	e.CS.OnChange = func(level bool) {
+		if level && !e.streaming() {
+			e.finish()
+		}
+	}
+	e.RST.OnChange = func(level bool) {
+		if level {
+			e.reset()
+		}
+	}
+	return e
+}
+
+// Func AttachSRAM puts a fake SRAM of size bytes on the bus, Devices
+// returned from then on keep their framebuffers in it
+func (e *Emulator) AttachSRAM(size int) *FakeSRAM {
+	e.SRAM = NewFakeSRAM(size)
+	e.SRAMCS = &Pin{Level: true}
+	return e.SRAM
+}
+
+// Func Device returns an il0373.Device wired to the emulator, with the
+// refresh delays taken out
+func (e *Emulator) Device() *il0373.Device {
+	var sramCS epd.Pin
+	sramSize := il0373.DefaultSRAMSize
+	if e.SRAM != nil {
+		sramCS, sramSize = e.SRAMCS, len(e.SRAM.Mem)
+	}
+	d := il0373.New(e.Width, e.Height, e, e.CS, e.DC, sramCS, e.RST, e.BUSY)
+	d.SRAMSize = sramSize
+	d.RefreshDelay = 0
+	d.BusyTimeout = time.Second
+	return d
+}
+
+// Func Pins returns the emulator's pins by the names il0373.Device.Trace uses,
+// ready to hand to an il0373.Replayer. SRAM_CS is only there once an SRAM is
+// attached.
+func (e *Emulator) Pins() map[string]epd.Pin {
+	pins := map[string]epd.Pin{
+		"CS":   e.CS,
+		"DC":   e.DC,
+		"RST":  e.RST,
+		"BUSY": e.BUSY,
+	}
+	if e.SRAM != nil {
+		pins["SRAM_CS"] = e.SRAMCS
+	}
+	return pins
+}
+
+func (e *Emulator) violation(format string, args ...interface{}) {
+	e.Violations = append(e.Violations, fmt.Errorf("il0373test: "+format, args...))
+}
+
+func (e *Emulator) reset() {
+	e.finish()
+	e.PoweredOn = false
+	e.Asleep = false
+	e.inCmd = false
+}
+
+// Func Tx clocks w into the controller. Only the temperature sensor is ever
+// read back, every other byte of r (which may be w itself) is zeroed once the
+// matching byte of w has been consumed.
+func (e *Emulator) Tx(w, r []byte) error {
+	if e.SRAM != nil && !e.SRAMCS.Level {
+		if !e.CS.Level {
+			e.violation("%d bytes clocked with both the panel and SRAM selected", len(w))
+		}
+		return e.SRAM.Tx(w, r)
+	}
+	if e.CS.Level {
+		e.violation("%d bytes clocked with CS high", len(w))
+		w = nil
+	}
+	for i := range r {
+		var out byte
+		if i < len(w) {
+			if e.DC.Level {
+				out = e.data(w[i])
+			} else {
+				e.command(w[i])
+			}
+		}
+		r[i] = out
+	}
+	for i := len(r); i < len(w); i++ {
+		if e.DC.Level {
+			e.data(w[i])
+		} else {
+			e.command(w[i])
+		}
+	}
+	return nil
+}
+
+func (e *Emulator) Transfer(b byte) (byte, error) {
+	return 0, e.Tx([]byte{b}, nil)
+}
+
+func (e *Emulator) command(b byte) {
+	e.finish()
+	if e.Asleep {
+		e.violation("command 0x%02x sent in deep sleep", b)
+	}
+	e.cmd = b
+	e.inCmd = true
+	e.args = e.args[:0]
+	e.ramPtr = 0
+
+	switch b {
+	case il0373.IL0373_POWER_ON:
+		e.PoweredOn = true
+	case il0373.IL0373_POWER_OFF:
+		e.PoweredOn = false
+	case il0373.IL0373_DISPLAY_REFRESH:
+		if !e.PoweredOn {
+			e.violation("refresh while powered off")
+		}
+		e.latch(0, len(e.RAM1))
+		e.Refreshes++
+	}
+}
+
+// Func data handles one data byte and returns what the controller drives back
+func (e *Emulator) data(b byte) byte {
+	if !e.inCmd {
+		e.violation("data 0x%02x without a command", b)
+		return 0
+	}
+
+	switch e.cmd {
+	case il0373.IL0373_TSC:
+		// 11 bit two's complement in D[10:0], 0.125C per LSB, MSB first
+		raw := uint16(int16(e.Temperature/125) << 5)
+		out := byte(raw >> 8)
+		if len(e.args) == 1 {
+			out = byte(raw)
+		}
+		e.args = append(e.args, b)
+		return out
+	case il0373.IL0373_DTM1, il0373.IL0373_DTM2:
+		ram := e.ram(e.cmd)
+		if e.ramPtr >= len(ram) {
+			e.violation("command 0x%02x overflowed panel RAM", e.cmd)
+			return 0
+		}
+		ram[e.ramPtr] = b
+		e.ramPtr++
+	case il0373.IL0373_PDTM1, il0373.IL0373_PDTM2:
+		if len(e.args) < 6 {
+			e.args = append(e.args, b)
+			return 0
+		}
+		x, y, w, h := window(e.args)
+		stride := e.Width / 8
+		row, col := e.ramPtr/(w/8), e.ramPtr%(w/8)
+		if w == 0 || row >= h || x+w > e.Width || y+h > e.Height {
+			e.violation("partial data outside window")
+			return 0
+		}
+		e.ram(e.cmd)[(y+row)*stride+x/8+col] = b
+		e.ramPtr++
+	default:
+		e.args = append(e.args, b)
+	}
+	return 0
+}
+
+// Func streaming reports whether the current command writes panel RAM
+func (e *Emulator) streaming() bool {
+	switch e.cmd {
+	case il0373.IL0373_DTM1, il0373.IL0373_DTM2, il0373.IL0373_PDTM1, il0373.IL0373_PDTM2:
+		return e.inCmd
+	}
+	return false
+}
+
+func (e *Emulator) ram(cmd byte) []byte {
+	if cmd == il0373.IL0373_DTM1 || cmd == il0373.IL0373_PDTM1 {
+		return e.RAM1
+	}
+	return e.RAM2
+}
+
+// Func window decodes the X, Y, W, L parameters of the partial commands
+func window(args []byte) (x, y, w, h int) {
+	return int(args[0]) &^ 7, int(args[1])<<8 | int(args[2]), int(args[3]) &^ 7, int(args[4])<<8 | int(args[5])
+}
+
+// Func finish applies the command whose transaction just ended
+func (e *Emulator) finish() {
+	if !e.inCmd {
+		return
+	}
+	e.inCmd = false
+	args := e.args
+
+	want := -1
+	switch e.cmd {
+	case il0373.IL0373_PANEL_SETTING:
+		if len(args) == 1 || len(args) == 2 {
+			e.PanelSetting = append([]byte(nil), args...)
+		} else {
+			want = 1
+		}
+	case il0373.IL0373_POWER_SETTING:
+		if len(args) == 4 || len(args) == 5 {
+			e.PowerSetting = append([]byte(nil), args...)
+		} else {
+			want = 5
+		}
+	case il0373.IL0373_BOOSTER_SOFT_START:
+		want = 3
+		e.BoosterSoftStart = append([]byte(nil), args...)
+	case il0373.IL0373_PLL:
+		want = 1
+		if len(args) > 0 {
+			e.PLL = args[0]
+		}
+	case il0373.IL0373_CDI:
+		want = 1
+		if len(args) > 0 {
+			e.CDI = args[0]
+		}
+	case il0373.IL0373_VCM_DC_SETTING:
+		want = 1
+		if len(args) > 0 {
+			e.VCMDC = args[0]
+		}
+	case il0373.IL0373_RESOLUTION:
+		want = 3
+		if len(args) == 3 {
+			e.ResWidth = int(args[0]) &^ 7
+			e.ResHeight = int(args[1])<<8 | int(args[2])
+			if e.ResWidth != e.Width || e.ResHeight != e.Height {
+				e.violation("resolution %dx%d doesn't match the %dx%d panel", e.ResWidth, e.ResHeight, e.Width, e.Height)
+			}
+		}
+	case il0373.IL0373_DEEP_SLEEP:
+		want = 1
+		if len(args) == 1 && args[0] == 0xA5 {
+			e.Asleep = true
+		} else {
+			e.violation("deep sleep without check code 0xA5")
+		}
+	case il0373.IL0373_PDRF:
+		want = 6
+		if len(args) == 6 {
+			if !e.PoweredOn {
+				e.violation("partial refresh while powered off")
+			}
+			x, y, w, h := window(args)
+			stride := e.Width / 8
+			for row := y; row < y+h && row < e.Height; row++ {
+				start := row*stride + x/8
+				end := start + w/8
+				e.latch(start, end)
+			}
+			e.PartialRefreshes++
+		}
+	case il0373.IL0373_TSE:
+		want = 1
+		if len(args) > 0 {
+			e.TSE = args[0]
+		}
+	case il0373.IL0373_TSC:
+		// Either starts a conversion or reads the result back
+		if len(args) != 0 && len(args) != 2 {
+			want = 2
+		}
+	case il0373.IL0373_LUT1, il0373.IL0373_LUTWW, il0373.IL0373_LUTBW, il0373.IL0373_LUTWB,
+		il0373.IL0373_LUTBB, il0373.IL0373_LUTR0, il0373.IL0373_LUTR1:
+		e.LUTs[e.cmd] = append([]byte(nil), args...)
+	case il0373.IL0373_POWER_ON, il0373.IL0373_POWER_OFF, il0373.IL0373_DISPLAY_REFRESH:
+		want = 0
+	case il0373.IL0373_DTM1, il0373.IL0373_DTM2, il0373.IL0373_PDTM1, il0373.IL0373_PDTM2:
+		// Data is streamed straight into RAM
+	default:
+		e.violation("unknown command 0x%02x", e.cmd)
+	}
+
+	if want >= 0 && len(args) != want {
+		e.violation("command 0x%02x sent %d data bytes, want %d", e.cmd, len(args), want)
+	}
+}
+
+// Func latch shows RAM bytes start to end on the panel. A DDX bit of 0
+// inverts that plane, and PANEL_SETTING bit 4 selects black/white mode.
+func (e *Emulator) latch(start, end int) {
+	ddx := e.CDI >> 4 & 0x3
+	mono := len(e.PanelSetting) > 0 && e.PanelSetting[0]&(1<<4) != 0
+	for i := start; i < end; i++ {
+		b1, b2 := e.RAM1[i], e.RAM2[i]
+		if ddx&0x1 == 0 {
+			b1 = ^b1
+		}
+		if ddx&0x2 == 0 {
+			b2 = ^b2
+		}
+		if mono {
+			b2 = 0xFF
+		}
+		e.Panel1[i], e.Panel2[i] = b1, b2
+	}
+}
+
+// Func At returns the color the panel shows at x, y. Both planes store a 0
+// bit for an inked pixel and red wins over black.
+func (e *Emulator) At(x, y int) color.RGBA {
+	index := (y*e.Width + x) / 8
+	bit := byte(0x80) >> (x & 7)
+	switch {
+	case e.Panel2[index]&bit == 0:
+		return color.RGBA{0xFF, 0x00, 0x00, 0xFF}
+	case e.Panel1[index]&bit == 0:
+		return color.RGBA{0x00, 0x00, 0x00, 0xFF}
+	default:
+		return color.RGBA{0xFF, 0xFF, 0xFF, 0xFF}
+	}
+}
+
+// Func Image renders what the panel shows
+func (e *Emulator) Image() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, e.Width, e.Height))
+	for y := 0; y < e.Height; y++ {
+		for x := 0; x < e.Width; x++ {
+			img.SetRGBA(x, y, e.At(x, y))
+		}
+	}
+	return img
+}
+
+// Func WritePNG renders what the panel shows as a PNG
+func (e *Emulator) WritePNG(w io.Writer) error {
+	return png.Encode(w, e.Image())
+}
@@ -0,0 +1,38 @@
+package il0373test
+
+import "tinygo.org/x/drivers"
+
+// Type SPI wraps a bus, counting the transfers made on it and failing them
+// on demand. A nil Bus discards everything.
+type SPI struct {
+	Bus drivers.SPI
+
+	// Calls counts Tx calls and Bytes the bytes written by them
+	Calls int
+	Bytes int
+
+	// Err, if set, fails every Tx call after the first FailAfter
+	Err       error
+	FailAfter int
+}
+
+func (s *SPI) Tx(w, r []byte) error {
+	s.Calls++
+	if s.Err != nil && s.Calls > s.FailAfter {
+		return s.Err
+	}
+	s.Bytes += len(w)
+	if s.Bus == nil {
+		for i := range r {
+			r[i] = 0
+		}
+		return nil
+	}
+	return s.Bus.Tx(w, r)
+}
+
+func (s *SPI) Transfer(b byte) (byte, error) {
+	buf := []byte{b}
+	err := s.Tx(buf, buf)
+	return buf[0], err
+}
@@ -0,0 +1,93 @@
+// Package il0373test provides host-side fakes of the hardware around the
+// IL0373 so the driver can be exercised without a badge.
+package il0373test
+
+import (
+	"errors"
+
+	"github.com/davidadeleon/gophercon2022Badge/il0373"
+)
+
+var ErrUnknownInstruction = errors.New("il0373test: unknown sram instruction")
+
+// Type FakeSRAM emulates a 23K640 style SPI SRAM. It satisfies drivers.SPI and
+// treats every Tx call as one complete CS-low transaction, which is how
+// il0373.SRAM talks to the chip.
+type FakeSRAM struct {
+	Mem    []byte
+	Status byte
+
+	// Transactions counts the Tx calls seen, Err makes them all fail
+	Transactions int
+	Err          error
+}
+
+// Func NewFakeSRAM returns a fake SRAM of size bytes in byte mode, as the real
+// part powers up
+func NewFakeSRAM(size int) *FakeSRAM {
+	return &FakeSRAM{
+		Mem:    make([]byte, size),
+		Status: il0373.SRAM_MODE_BYTE,
+	}
+}
+
+func (f *FakeSRAM) Tx(w, r []byte) error {
+	f.Transactions++
+	if f.Err != nil {
+		return f.Err
+	}
+	if len(w) == 0 {
+		return nil
+	}
+
+	switch w[0] {
+	case il0373.SRAM_WRSR:
+		if len(w) > 1 {
+			f.Status = w[1]
+		}
+	case il0373.SRAM_RDSR:
+		if len(r) > 1 {
+			r[1] = f.Status
+		}
+	case il0373.SRAM_READ:
+		if len(w) < 3 {
+			return nil
+		}
+		addr := int(w[1])<<8 | int(w[2])
+		for i := 3; i < len(r); i++ {
+			r[i] = f.Mem[f.next(addr, i-3)]
+		}
+	case il0373.SRAM_WRITE:
+		if len(w) < 3 {
+			return nil
+		}
+		addr := int(w[1])<<8 | int(w[2])
+		for i := 3; i < len(w); i++ {
+			f.Mem[f.next(addr, i-3)] = w[i]
+		}
+	default:
+		return ErrUnknownInstruction
+	}
+	return nil
+}
+
+func (f *FakeSRAM) Transfer(b byte) (byte, error) {
+	buf := []byte{b}
+	err := f.Tx(buf, buf)
+	return buf[0], err
+}
+
+// Func next returns the address of the n'th byte of a transfer starting at
+// addr, wrapping the way the selected mode does
+func (f *FakeSRAM) next(addr, n int) int {
+	switch f.Status & 0xC0 {
+	case il0373.SRAM_MODE_SEQUENTIAL:
+		return (addr + n) % len(f.Mem)
+	case il0373.SRAM_MODE_PAGE:
+		// 32 byte pages, the address wraps within the page
+		return ((addr &^ 0x1F) | ((addr + n) & 0x1F)) % len(f.Mem)
+	default:
+		// Byte mode only ever touches addr
+		return addr % len(f.Mem)
+	}
+}
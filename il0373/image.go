@@ -0,0 +1,103 @@
+package il0373
+
+import (
+	"image"
+	"image/color"
+)
+
+var (
+	bitmapWhite = color.RGBA{0xFF, 0xFF, 0xFF, 0xFF}
+	bitmapBlack = color.RGBA{0x00, 0x00, 0x00, 0xFF}
+	bitmapRed   = color.RGBA{0xFF, 0x00, 0x00, 0xFF}
+)
+
+// Type Bitmap is a packed 1 bit per pixel image for either or both planes.
+// Rows are MSB first and padded to a whole byte, a set bit inks the pixel and
+// red wins over black. A nil plane is left blank.
+type Bitmap struct {
+	Width  int
+	Height int
+	Black  []byte
+	Red    []byte
+}
+
+func (b *Bitmap) stride() int {
+	return (b.Width + 7) / 8
+}
+
+func (b *Bitmap) bit(plane []byte, x, y int) bool {
+	if plane == nil {
+		return false
+	}
+	index := y*b.stride() + x/8
+	return index < len(plane) && plane[index]&(0x80>>(x&7)) != 0
+}
+
+// Func Ink returns the panel color of the pixel at x, y
+func (b *Bitmap) Ink(x, y int) int {
+	switch {
+	case b.bit(b.Red, x, y):
+		return RED
+	case b.bit(b.Black, x, y):
+		return BLACK
+	default:
+		return WHITE
+	}
+}
+
+func (b *Bitmap) ColorModel() color.Model {
+	return color.RGBAModel
+}
+
+func (b *Bitmap) Bounds() image.Rectangle {
+	return image.Rect(0, 0, b.Width, b.Height)
+}
+
+func (b *Bitmap) At(x, y int) color.Color {
+	if !(image.Point{x, y}.In(b.Bounds())) {
+		return color.RGBA{}
+	}
+	switch b.Ink(x, y) {
+	case RED:
+		return bitmapRed
+	case BLACK:
+		return bitmapBlack
+	default:
+		return bitmapWhite
+	}
+}
+
+// Func DisplayImage draws img into the framebuffers with its top left corner
+// at x, y in the current rotation. Colors are quantized with the Palette and
+// pixels below the AlphaThreshold are skipped, a *Bitmap is copied straight
+// into the planes. The panel isn't refreshed, call Display afterwards.
+func (d *Device) DisplayImage(x, y int, img image.Image) error {
+	if d.blackFrameBuffer == nil {
+		return ErrInvalidState
+	}
+	bounds := img.Bounds()
+	width, height := d.Size()
+	if x < 0 || y < 0 || x+bounds.Dx() > int(width) || y+bounds.Dy() > int(height) {
+		return ErrBadDimensions
+	}
+
+	if b, ok := img.(*Bitmap); ok {
+		for by := 0; by < b.Height; by++ {
+			for bx := 0; bx < b.Width; bx++ {
+				d.Pixel(x+bx, y+by, b.Ink(bx, by))
+			}
+		}
+		return nil
+	}
+
+	for iy := bounds.Min.Y; iy < bounds.Max.Y; iy++ {
+		for ix := bounds.Min.X; ix < bounds.Max.X; ix++ {
+			c := color.RGBAModel.Convert(img.At(ix, iy)).(color.RGBA)
+			if c.A < d.AlphaThreshold {
+				continue
+			}
+			d.Pixel(x+ix-bounds.Min.X, y+iy-bounds.Min.Y, d.Palette.Nearest(c))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package il0373
+
+import "image/color"
+
+// Type PaletteEntry maps a drawing color onto one of the panel colors, BLACK,
+// WHITE or RED
+type PaletteEntry struct {
+	Color color.RGBA
+	Ink   int
+}
+
+// Type Palette is the set of colors SetPixel quantizes to
+type Palette []PaletteEntry
+
+// Func DefaultPalette returns pure white, black and red
+func DefaultPalette() Palette {
+	return Palette{
+		{Color: color.RGBA{0xFF, 0xFF, 0xFF, 0xFF}, Ink: WHITE},
+		{Color: color.RGBA{0x00, 0x00, 0x00, 0xFF}, Ink: BLACK},
+		{Color: color.RGBA{0xFF, 0x00, 0x00, 0xFF}, Ink: RED},
+	}
+}
+
+// Func Nearest returns the panel color of the entry closest to c, weighting
+// the channels roughly by how bright they look. An empty palette maps
+// everything to WHITE.
+func (p Palette) Nearest(c color.RGBA) int {
+	ink := WHITE
+	best := -1
+	for _, e := range p {
+		dr := int(c.R) - int(e.Color.R)
+		dg := int(c.G) - int(e.Color.G)
+		db := int(c.B) - int(e.Color.B)
+		dist := 3*dr*dr + 4*dg*dg + 2*db*db
+		if best < 0 || dist < best {
+			best = dist
+			ink = e.Ink
+		}
+	}
+	return ink
+}
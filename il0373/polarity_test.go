@@ -0,0 +1,158 @@
+package il0373_test
+
+import (
+	"testing"
+
+	"github.com/davidadeleon/gophercon2022Badge/il0373"
+	"github.com/davidadeleon/gophercon2022Badge/il0373/il0373test"
+)
+
+// Func drawMarks draws a black pixel at 0,0 and a red one at 1,0 on white
+func drawMarks(d *il0373.Device) {
+	d.Fill(il0373.WHITE)
+	d.Pixel(0, 0, il0373.BLACK)
+	d.Pixel(1, 0, il0373.RED)
+}
+
+func checkMarks(t *testing.T, e *il0373test.Emulator) {
+	t.Helper()
+	checkViolations(t, e)
+	checkPixel(t, e, 0, 0, black)
+	checkPixel(t, e, 1, 0, red)
+	checkPixel(t, e, 2, 0, white)
+	checkPixel(t, e, 0, 1, white)
+}
+
+func TestSetDataPolarity(t *testing.T) {
+	t.Parallel()
+	for _, polarity := range []il0373.DataPolarity{
+		il0373.PolarityNormal,
+		il0373.PolarityInvertBlack,
+		il0373.PolarityInvertRed,
+		il0373.PolarityInvertBlack | il0373.PolarityInvertRed,
+	} {
+		polarity := polarity
+		t.Run("", func(t *testing.T) {
+			t.Parallel()
+			e, d := newPanel(t)
+			// Drawn before the change, the buffers must follow it
+			drawMarks(d)
+			if err := d.SetDataPolarity(polarity); err != nil {
+				t.Fatal(err)
+			}
+			if err := d.Display(); err != nil {
+				t.Fatal(err)
+			}
+			checkMarks(t, e)
+
+			if ddx := e.CDI >> 4 & 0x3; ddx != byte(0x3&^polarity) {
+				t.Errorf("DDX = %02b for polarity %d", ddx, polarity)
+			}
+			inked := e.RAM1[0]&0x80 != 0
+			if want := polarity&il0373.PolarityInvertBlack != 0; inked != want {
+				t.Errorf("black plane bit = %v for polarity %d", inked, polarity)
+			}
+
+			// And drawing after it
+			drawMarks(d)
+			d.Pixel(0, 0, il0373.WHITE)
+			if err := d.Display(); err != nil {
+				t.Fatal(err)
+			}
+			checkPixel(t, e, 0, 0, white)
+			checkPixel(t, e, 1, 0, red)
+		})
+	}
+}
+
+func TestDataPolarityFromConfig(t *testing.T) {
+	t.Parallel()
+	e := il0373test.NewEmulator(width, height)
+	d := e.Device()
+	d.Config.DataPolarity = il0373.PolarityInvertBlack | il0373.PolarityInvertRed
+	if err := d.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	drawMarks(d)
+	if err := d.Display(); err != nil {
+		t.Fatal(err)
+	}
+	checkMarks(t, e)
+}
+
+func TestDataPolaritySRAM(t *testing.T) {
+	t.Parallel()
+	e, d := newSRAMPanel(t, true)
+	drawMarks(d)
+	if err := d.Present(); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SetDataPolarity(il0373.PolarityInvertBlack); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Display(); err != nil {
+		t.Fatal(err)
+	}
+	checkMarks(t, e)
+
+	// Present swaps in the old front buffers, drawing on them again uses the
+	// new polarity
+	drawMarks(d)
+	if err := d.Present(); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Display(); err != nil {
+		t.Fatal(err)
+	}
+	checkMarks(t, e)
+}
+
+func TestCleanDataPolarity(t *testing.T) {
+	t.Parallel()
+	e, d := newPanel(t)
+	if err := d.SetDataPolarity(il0373.PolarityInvertBlack | il0373.PolarityInvertRed); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Clean(); err != nil {
+		t.Fatal(err)
+	}
+	checkViolations(t, e)
+	for _, p := range [][2]int{{0, 0}, {width - 1, height - 1}, {60, 150}} {
+		checkPixel(t, e, p[0], p[1], white)
+	}
+}
+
+func TestPowerOffCDI(t *testing.T) {
+	t.Parallel()
+	e, d := newPanel(t)
+	// VBD 00, DDX 01 and the interval, whatever the border and polarity
+	d.Config.Border = il0373.BorderRed
+	d.Config.DataPolarity = il0373.PolarityInvertRed
+	if err := d.PowerUp(); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.PowerDown(); err != nil {
+		t.Fatal(err)
+	}
+	if e.CDI != 0x17 {
+		t.Errorf("power off CDI = %02x, want 17", e.CDI)
+	}
+}
+
+func TestMonochrome(t *testing.T) {
+	t.Parallel()
+	e := il0373test.NewEmulator(width, height)
+	d := e.Device()
+	d.Config.Monochrome = true
+	if err := d.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	drawMarks(d)
+	if err := d.Display(); err != nil {
+		t.Fatal(err)
+	}
+	checkViolations(t, e)
+	checkPixel(t, e, 0, 0, black)
+	// Red plane ignored in black/white mode
+	checkPixel(t, e, 1, 0, white)
+}
@@ -0,0 +1,34 @@
+package il0373
+
+// Func Present swaps the back buffers, where drawing goes, with the front
+// buffers that Display, DisplayRegion and DisplayAsync upload. Drawing then
+// goes to the frame that was on the front, so redraw it in full. If the front
+// buffers are being uploaded Present waits for that, but not for the refresh
+// that follows. It's a no-op unless DoubleBuffered.
+func (d *Device) Present() error {
+	if !d.DoubleBuffered {
+		return nil
+	}
+	if !d.ready() {
+		return ErrInvalidState
+	}
+
+	// Uploads hold frontMu while they read the front buffers
+	d.frontMu.Lock()
+	defer d.frontMu.Unlock()
+	// DisplayAsync snapshots the front buffers under this lock
+	d.async.mu.Lock()
+	defer d.async.mu.Unlock()
+
+	if d.sram == nil {
+		// framebuf1 and framebuf2 point at buffer1 and buffer2
+		d.buffer1, d.front1 = d.front1, d.buffer1
+		d.buffer2, d.front2 = d.front2, d.buffer2
+		return nil
+	}
+
+	d.backOffset, d.frontOffset = d.frontOffset, d.backOffset
+	d.framebuf1.Store = d.sram.Region(d.backOffset, d.buffer1_size)
+	d.framebuf2.Store = d.sram.Region(d.backOffset+d.buffer1_size, d.buffer2_size)
+	return nil
+}
@@ -0,0 +1,118 @@
+package il0373_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/davidadeleon/gophercon2022Badge/il0373"
+	"github.com/davidadeleon/gophercon2022Badge/il0373/il0373test"
+	"tinygo.org/x/drivers"
+)
+
+func newDoubleBufferedPanel(t *testing.T) (*il0373test.Emulator, *il0373.Device) {
+	t.Helper()
+	e := il0373test.NewEmulator(width, height)
+	d := e.Device()
+	d.DoubleBuffered = true
+	if err := d.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	return e, d
+}
+
+// Type refreshSPI signals once the refresh command has been sent, after the
+// frame upload
+type refreshSPI struct {
+	drivers.SPI
+	dc        *il0373test.Pin
+	refreshed chan struct{}
+}
+
+func (s *refreshSPI) Tx(w, r []byte) error {
+	// r may be w, check it before the emulator overwrites it
+	refresh := !s.dc.Level && len(w) == 1 && w[0] == il0373.IL0373_DISPLAY_REFRESH
+	err := s.SPI.Tx(w, r)
+	if refresh {
+		select {
+		case s.refreshed <- struct{}{}:
+		default:
+		}
+	}
+	return err
+}
+
+func TestPresent(t *testing.T) {
+	t.Parallel()
+	e, d := newDoubleBufferedPanel(t)
+	d.Fill(il0373.WHITE)
+	if err := d.Present(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Drawing goes to the back buffers, Display sends the front ones
+	d.Fill(il0373.BLACK)
+	if err := d.Display(); err != nil {
+		t.Fatal(err)
+	}
+	checkPixel(t, e, 5, 5, white)
+
+	if err := d.Present(); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Display(); err != nil {
+		t.Fatal(err)
+	}
+	checkPixel(t, e, 5, 5, black)
+
+	// The buffers were swapped, not copied: the back now holds the white frame
+	d.Pixel(6, 6, il0373.RED)
+	if err := d.Present(); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Display(); err != nil {
+		t.Fatal(err)
+	}
+	checkViolations(t, e)
+	checkPixel(t, e, 5, 5, white)
+	checkPixel(t, e, 6, 6, red)
+}
+
+func TestPresentDuringRefresh(t *testing.T) {
+	t.Parallel()
+	e, d := newDoubleBufferedPanel(t)
+	d.RefreshDelay = time.Second
+	spy := &refreshSPI{SPI: d.SPI, dc: e.DC, refreshed: make(chan struct{}, 1)}
+	d.SPI = spy
+
+	d.Fill(il0373.WHITE)
+	if err := d.Present(); err != nil {
+		t.Fatal(err)
+	}
+	d.Fill(il0373.BLACK)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- d.Display()
+	}()
+
+	// The upload is over, Present mustn't wait for the panel to settle
+	<-spy.refreshed
+	start := time.Now()
+	if err := d.Present(); err != nil {
+		t.Fatal(err)
+	}
+	if wait := time.Since(start); wait > time.Second/2 {
+		t.Errorf("Present waited %v for the refresh", wait)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	checkPixel(t, e, 5, 5, white)
+
+	d.RefreshDelay = 0
+	if err := d.Display(); err != nil {
+		t.Fatal(err)
+	}
+	checkViolations(t, e)
+	checkPixel(t, e, 5, 5, black)
+}
@@ -30,7 +30,13 @@ const (
 	IL0373_LUTBW              = 0x22
 	IL0373_LUTWB              = 0x23
 	IL0373_LUTBB              = 0x24
+	IL0373_LUTR0              = 0x25
+	IL0373_LUTR1              = 0x26
 	IL0373_PLL                = 0x30
+	IL0373_TSC                = 0x40
+	IL0373_TSE                = 0x41
+	IL0373_TSW                = 0x42
+	IL0373_TSR                = 0x43
 	IL0373_CDI                = 0x50
 	IL0373_RESOLUTION         = 0x61
 	IL0373_VCM_DC_SETTING     = 0x82
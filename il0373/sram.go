@@ -0,0 +1,247 @@
+package il0373
+
+import (
+	"sync"
+
+	"github.com/davidadeleon/gophercon2022Badge/epd"
+	"tinygo.org/x/drivers"
+)
+
+// Microchip 23K640 / 23K256 serial SRAM, as fitted to the Adafruit ePaper
+// FeatherWings, used to hold the framebuffers outside of MCU RAM.
+const (
+	SRAM_READ  = 0x03
+	SRAM_WRITE = 0x02
+	SRAM_RDSR  = 0x05
+	SRAM_WRSR  = 0x01
+
+	// Status register operating modes
+	SRAM_MODE_BYTE       = 0x00
+	SRAM_MODE_PAGE       = 0x80
+	SRAM_MODE_SEQUENTIAL = 0x40
+)
+
+// The FeatherWings carry a 23K256, 32KB, enough for both planes of the
+// 128x296 panel twice over. Boards with a 23K640 can set Device.SRAMSize to
+// 8KB, which only fits panels up to 65536 pixels.
+const DefaultSRAMSize = 32 * 1024
+
+// Type SRAM drives a 23K640 style SPI SRAM.
+//
+// Every SRAM transaction (instruction, address and data) is sent as a single
+// Tx call while CS is held low, so a host-side fake bus can treat each Tx as
+// one complete transaction.
+type SRAM struct {
+	SPI  drivers.SPI
+	CS   epd.Pin
+	Size int
+
+	// lock guards the bus, the Device hands in its own bus lock so SRAM access
+	// never overlaps with a transfer to the panel
+	lock sync.Locker
+	mu   sync.Mutex
+
+	mode  byte
+	txBuf []byte
+	err   error
+}
+
+// Func NewSRAM returns an SRAM of size bytes on the given bus
+func NewSRAM(bus drivers.SPI, csPin epd.Pin, size int) *SRAM {
+	s := &SRAM{
+		SPI:  bus,
+		CS:   csPin,
+		Size: size,
+	}
+	s.lock = &s.mu
+	s.txBuf = make([]byte, 3+DefaultChunkSize)
+	return s
+}
+
+// Func Configure sets up the CS pin and puts the SRAM in sequential mode so
+// reads and writes can run across page boundaries
+func (s *SRAM) Configure() error {
+	epd.Configure(s.CS, epd.PinOutput)
+	s.CS.High()
+	return s.SetMode(SRAM_MODE_SEQUENTIAL)
+}
+
+// Func SetMode writes the status register to select byte, page or sequential
+// operation
+func (s *SRAM) SetMode(mode byte) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if err := s.tx([]byte{SRAM_WRSR, mode}, nil); err != nil {
+		return err
+	}
+	s.mode = mode
+	return nil
+}
+
+// Func Mode reads back the status register
+func (s *SRAM) Mode() (byte, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	buf := []byte{SRAM_RDSR, 0}
+	if err := s.tx(buf, buf); err != nil {
+		return 0, err
+	}
+	return buf[1], nil
+}
+
+// Func Read fills buf with the contents of the SRAM starting at addr
+func (s *SRAM) Read(addr int, buf []byte) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.read(addr, buf)
+}
+
+// Func Write stores data in the SRAM starting at addr
+func (s *SRAM) Write(addr int, data []byte) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.write(addr, data)
+}
+
+// Func Fill sets n bytes starting at addr to val
+func (s *SRAM) Fill(addr int, val byte, n int) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.fill(addr, val, n)
+}
+
+// Func Err returns the first error hit by a framebuffer access. Framebuffer
+// drawing can't return errors so they are kept here and reported by Display.
+func (s *SRAM) Err() error {
+	return s.err
+}
+
+// Func Region returns a framebuffer.Storage covering size bytes from offset
+func (s *SRAM) Region(offset, size int) *SRAMRegion {
+	return &SRAMRegion{sram: s, offset: offset, size: size}
+}
+
+func (s *SRAM) read(addr int, buf []byte) error {
+	if addr < 0 || addr+len(buf) > s.Size {
+		return ErrBadDimensions
+	}
+	for len(buf) > 0 {
+		n := len(s.txBuf) - 3
+		if n > len(buf) {
+			n = len(buf)
+		}
+		tx := s.txBuf[:3+n]
+		tx[0] = SRAM_READ
+		tx[1] = byte(addr >> 8)
+		tx[2] = byte(addr)
+		for i := 3; i < len(tx); i++ {
+			tx[i] = 0
+		}
+		if err := s.tx(tx, tx); err != nil {
+			return err
+		}
+		copy(buf, tx[3:])
+		buf = buf[n:]
+		addr += n
+	}
+	return nil
+}
+
+func (s *SRAM) write(addr int, data []byte) error {
+	if addr < 0 || addr+len(data) > s.Size {
+		return ErrBadDimensions
+	}
+	for len(data) > 0 {
+		n := len(s.txBuf) - 3
+		if n > len(data) {
+			n = len(data)
+		}
+		tx := s.txBuf[:3+n]
+		tx[0] = SRAM_WRITE
+		tx[1] = byte(addr >> 8)
+		tx[2] = byte(addr)
+		copy(tx[3:], data[:n])
+		if err := s.tx(tx, nil); err != nil {
+			return err
+		}
+		data = data[n:]
+		addr += n
+	}
+	return nil
+}
+
+func (s *SRAM) fill(addr int, val byte, n int) error {
+	if addr < 0 || addr+n > s.Size {
+		return ErrBadDimensions
+	}
+	for n > 0 {
+		c := len(s.txBuf) - 3
+		if c > n {
+			c = n
+		}
+		tx := s.txBuf[:3+c]
+		tx[0] = SRAM_WRITE
+		tx[1] = byte(addr >> 8)
+		tx[2] = byte(addr)
+		for i := 3; i < len(tx); i++ {
+			tx[i] = val
+		}
+		if err := s.tx(tx, nil); err != nil {
+			return err
+		}
+		n -= c
+		addr += c
+	}
+	return nil
+}
+
+func (s *SRAM) tx(w, r []byte) error {
+	s.CS.Low()
+	err := s.SPI.Tx(w, r)
+	s.CS.High()
+	if err != nil {
+		return &BusError{Cmd: w[0], Err: err}
+	}
+	return nil
+}
+
+// Type SRAMRegion is a window of the SRAM holding one framebuffer plane, it
+// satisfies framebuffer.Storage
+type SRAMRegion struct {
+	sram   *SRAM
+	offset int
+	size   int
+	one    [1]byte
+}
+
+func (r *SRAMRegion) Get(index int) byte {
+	r.sram.lock.Lock()
+	defer r.sram.lock.Unlock()
+	r.keep(r.sram.read(r.offset+index, r.one[:]))
+	return r.one[0]
+}
+
+func (r *SRAMRegion) Set(index int, val byte) {
+	r.sram.lock.Lock()
+	defer r.sram.lock.Unlock()
+	r.one[0] = val
+	r.keep(r.sram.write(r.offset+index, r.one[:]))
+}
+
+func (r *SRAMRegion) Fill(val byte) {
+	r.sram.lock.Lock()
+	defer r.sram.lock.Unlock()
+	r.keep(r.sram.fill(r.offset, val, r.size))
+}
+
+func (r *SRAMRegion) Len() int {
+	return r.size
+}
+
+func (r *SRAMRegion) keep(err error) {
+	if err != nil && r.sram.err == nil {
+		r.sram.err = err
+	}
+}
@@ -0,0 +1,177 @@
+package il0373_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/davidadeleon/gophercon2022Badge/il0373"
+	"github.com/davidadeleon/gophercon2022Badge/il0373/il0373test"
+)
+
+func newSRAM(t *testing.T, size int) (*il0373.SRAM, *il0373test.FakeSRAM) {
+	t.Helper()
+	fake := il0373test.NewFakeSRAM(size)
+	s := il0373.NewSRAM(fake, &il0373test.Pin{Level: true}, size)
+	if err := s.Configure(); err != nil {
+		t.Fatal(err)
+	}
+	return s, fake
+}
+
+func TestSRAMReadWrite(t *testing.T) {
+	s, fake := newSRAM(t, 1024)
+	if mode, err := s.Mode(); err != nil || mode != il0373.SRAM_MODE_SEQUENTIAL {
+		t.Fatalf("mode after Configure = 0x%02x, %v", mode, err)
+	}
+
+	// Longer than a transfer and across 32 byte pages
+	data := make([]byte, 600)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	if err := s.Write(30, data); err != nil {
+		t.Fatal(err)
+	}
+	got := make([]byte, len(data))
+	if err := s.Read(30, got); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) || !bytes.Equal(fake.Mem[30:630], data) {
+		t.Error("read back differs from what was written")
+	}
+
+	if err := s.Fill(1000, 0xA5, 24); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(fake.Mem[1000:], bytes.Repeat([]byte{0xA5}, 24)) {
+		t.Errorf("fill wrote %x", fake.Mem[1000:])
+	}
+
+	for _, addr := range []int{-1, 1000} {
+		if err := s.Write(addr, data[:30]); !errors.Is(err, il0373.ErrBadDimensions) {
+			t.Errorf("write of 30 bytes at %d: err = %v", addr, err)
+		}
+	}
+}
+
+func TestSRAMModes(t *testing.T) {
+	s, fake := newSRAM(t, 1024)
+	for _, tc := range []struct {
+		mode byte
+		// Where the bytes 1, 2, 3, 4 written at 30 should land
+		want map[int]byte
+	}{
+		// Sequential runs on past the end of the 32 byte page
+		{il0373.SRAM_MODE_SEQUENTIAL, map[int]byte{30: 1, 31: 2, 32: 3, 33: 4}},
+		// Page mode wraps round to the start of the page
+		{il0373.SRAM_MODE_PAGE, map[int]byte{30: 1, 31: 2, 0: 3, 1: 4}},
+		// Byte mode only ever writes the one address
+		{il0373.SRAM_MODE_BYTE, map[int]byte{30: 4}},
+	} {
+		for i := range fake.Mem {
+			fake.Mem[i] = 0
+		}
+		if err := s.SetMode(tc.mode); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Write(30, []byte{1, 2, 3, 4}); err != nil {
+			t.Fatal(err)
+		}
+		for addr, val := range fake.Mem {
+			if want := tc.want[addr]; val != want {
+				t.Errorf("mode 0x%02x: byte %d = %d, want %d", tc.mode, addr, val, want)
+			}
+		}
+	}
+}
+
+func TestSRAMBusError(t *testing.T) {
+	s, fake := newSRAM(t, 1024)
+	fake.Err = errBus
+	checkBusError(t, s.Write(0, []byte{1}), il0373.SRAM_WRITE)
+	checkBusError(t, s.Read(0, make([]byte, 1)), il0373.SRAM_READ)
+}
+
+// Func newSRAMPanel returns an initialized Device keeping its framebuffers in
+// an emulated SRAM of the default size
+func newSRAMPanel(t *testing.T, doubleBuffered bool) (*il0373test.Emulator, *il0373.Device) {
+	t.Helper()
+	e := il0373test.NewEmulator(width, height)
+	e.AttachSRAM(il0373.DefaultSRAMSize)
+	d := e.Device()
+	d.DoubleBuffered = doubleBuffered
+	if err := d.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	return e, d
+}
+
+func TestDisplayFromSRAM(t *testing.T) {
+	t.Parallel()
+	e, d := newSRAMPanel(t, false)
+	if black, _ := d.Buffers(); black.Store == nil {
+		t.Fatal("framebuffer isn't backed by the SRAM")
+	}
+
+	d.Fill(il0373.WHITE)
+	for x := 0; x < 40; x++ {
+		d.Pixel(x, 3, il0373.BLACK)
+		d.Pixel(x, 200, il0373.RED)
+	}
+	if err := d.Display(); err != nil {
+		t.Fatal(err)
+	}
+	checkViolations(t, e)
+	checkPixel(t, e, 0, 3, black)
+	checkPixel(t, e, 39, 200, red)
+	checkPixel(t, e, 40, 3, white)
+	checkPixel(t, e, width-1, height-1, white)
+
+	// Partial refreshes read each row of the window from the SRAM
+	d.Pixel(100, 100, il0373.BLACK)
+	d.Pixel(101, 101, il0373.RED)
+	if err := d.DisplayRegion(96, 96, 16, 8); err != nil {
+		t.Fatal(err)
+	}
+	checkViolations(t, e)
+	checkPixel(t, e, 100, 100, black)
+	checkPixel(t, e, 101, 101, red)
+	if e.PartialRefreshes != 1 {
+		t.Errorf("%d partial refreshes, want 1", e.PartialRefreshes)
+	}
+}
+
+func TestPresentFromSRAM(t *testing.T) {
+	t.Parallel()
+	e, d := newSRAMPanel(t, true)
+	d.Fill(il0373.WHITE)
+	if err := d.Present(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Drawing goes to the back buffers, Display sends the front ones
+	d.Fill(il0373.BLACK)
+	if err := d.Display(); err != nil {
+		t.Fatal(err)
+	}
+	checkPixel(t, e, 5, 5, white)
+
+	if err := d.Present(); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Display(); err != nil {
+		t.Fatal(err)
+	}
+	checkViolations(t, e)
+	checkPixel(t, e, 5, 5, black)
+}
+
+func TestSRAMTooSmall(t *testing.T) {
+	e := il0373test.NewEmulator(width, height)
+	e.AttachSRAM(8 * 1024)
+	d := e.Device()
+	if err := d.Initialize(); !errors.Is(err, il0373.ErrBadDimensions) {
+		t.Errorf("Initialize with an 8KB SRAM: err = %v", err)
+	}
+}
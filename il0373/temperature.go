@@ -0,0 +1,149 @@
+package il0373
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrTemperature = errors.New("il0373: panel outside safe operating temperature")
+
+// Type TemperaturePolicy decides what a refresh does when the panel is outside
+// Device.OperatingRange
+type TemperaturePolicy uint8
+
+const (
+	// TemperatureIgnore never reads the temperature unless LUTs need it
+	TemperatureIgnore TemperaturePolicy = iota
+	// TemperatureWarn prints a warning and refreshes anyway
+	TemperatureWarn
+	// TemperatureRefuse fails the refresh with ErrTemperature
+	TemperatureRefuse
+)
+
+// Type TemperatureRange is an inclusive range in milli-degrees Celsius
+type TemperatureRange struct {
+	Min int32
+	Max int32
+}
+
+func (r TemperatureRange) Contains(t int32) bool {
+	return t >= r.Min && t <= r.Max
+}
+
+// The IL0373 tri-color panels are specified for 0 to 50C
+var DefaultOperatingRange = TemperatureRange{Min: 0, Max: 50000}
+
+// Type LUT is a waveform for the LUT registers. VCOM is 44 bytes, the others
+// 42. The red LUTs are only sent when set.
+type LUT struct {
+	VCOM []byte
+	WW   []byte
+	BW   []byte
+	WB   []byte
+	BB   []byte
+	R0   []byte
+	R1   []byte
+}
+
+// Type TemperatureLUT is a waveform to use within a temperature range
+type TemperatureLUT struct {
+	Range TemperatureRange
+	LUT   LUT
+}
+
+// Func Temperature reads the panel temperature in milli-degrees Celsius. If
+// TemperatureFunc is set it's used instead, most FeatherWings don't wire the
+// controller's data line back so the internal sensor can't be read.
+func (d *Device) Temperature() (int32, error) {
+	d.busMu.Lock()
+	defer d.busMu.Unlock()
+	return d.temperature()
+}
+
+func (d *Device) temperature() (int32, error) {
+	if d.TemperatureFunc != nil {
+		return d.TemperatureFunc()
+	}
+
+	// Select the internal sensor with no offset, then trigger a conversion
+	if _, err := d.command(IL0373_TSE, []byte{0x00}, true); err != nil {
+		return 0, err
+	}
+	if _, err := d.command(IL0373_TSC, nil, true); err != nil {
+		return 0, err
+	}
+	if err := d.BusyWait(); err != nil {
+		return 0, err
+	}
+
+	if _, err := d.command(IL0373_TSC, nil, false); err != nil {
+		return 0, err
+	}
+	d.DC_PIN.High()
+	buf := d._buf[:2]
+	buf[0], buf[1] = 0, 0
+	err := d.SPI.Tx(buf, buf)
+	d.CS_PIN.High()
+	if err != nil {
+		return 0, &BusError{Cmd: IL0373_TSC, Err: err}
+	}
+
+	// 11 bit two's complement in D[10:0], 0.125C per LSB
+	raw := int16(uint16(buf[0])<<8|uint16(buf[1])) >> 5
+	return int32(raw) * 125, nil
+}
+
+// Func checkTemperature reads the temperature if the policy or the LUTs need
+// it, applies the policy and returns the LUT to upload, if any
+func (d *Device) checkTemperature() (*LUT, error) {
+	if d.TemperaturePolicy == TemperatureIgnore && len(d.LUTs) == 0 {
+		return nil, nil
+	}
+
+	t, err := d.temperature()
+	if err != nil {
+		return nil, err
+	}
+	d.LastTemperature = t
+	d.LastTemperatureAt = time.Now()
+
+	if !d.OperatingRange.Contains(t) {
+		switch d.TemperaturePolicy {
+		case TemperatureWarn:
+			println("[IL0373] panel temperature", t/1000, "C outside safe range")
+		case TemperatureRefuse:
+			return nil, ErrTemperature
+		}
+	}
+
+	for i := range d.LUTs {
+		if d.LUTs[i].Range.Contains(t) {
+			return &d.LUTs[i].LUT, nil
+		}
+	}
+	return nil, nil
+}
+
+func (d *Device) writeLUT(lut *LUT) error {
+	regs := []struct {
+		cmd  byte
+		data []byte
+	}{
+		{IL0373_LUT1, lut.VCOM},
+		{IL0373_LUTWW, lut.WW},
+		{IL0373_LUTBW, lut.BW},
+		{IL0373_LUTWB, lut.WB},
+		{IL0373_LUTBB, lut.BB},
+		{IL0373_LUTR0, lut.R0},
+		{IL0373_LUTR1, lut.R1},
+	}
+	for _, r := range regs {
+		if r.data == nil {
+			continue
+		}
+		if _, err := d.command(r.cmd, r.data, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
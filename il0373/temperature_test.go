@@ -0,0 +1,135 @@
+package il0373_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/davidadeleon/gophercon2022Badge/il0373"
+)
+
+func TestTemperature(t *testing.T) {
+	t.Parallel()
+	e, d := newPanel(t)
+	e.TSE = 0xFF
+	for _, temp := range []int32{25000, 0, 125, -125, -5000, -40000, 50000, 127875} {
+		e.Temperature = temp
+		got, err := d.Temperature()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != temp {
+			t.Errorf("Temperature() = %d, want %d", got, temp)
+		}
+	}
+	checkViolations(t, e)
+	if e.TSE != 0x00 {
+		t.Errorf("TSE = 0x%02x, want the internal sensor", e.TSE)
+	}
+}
+
+// Func testLUT returns a LUT filled with b so uploads can be told apart
+func testLUT(b byte) il0373.LUT {
+	reg := bytes.Repeat([]byte{b}, 42)
+	return il0373.LUT{
+		VCOM: bytes.Repeat([]byte{b}, 44),
+		WW:   reg,
+		BW:   reg,
+		WB:   reg,
+		BB:   reg,
+	}
+}
+
+func TestTemperatureLUTs(t *testing.T) {
+	t.Parallel()
+	cold, warm := testLUT(0xC0), testLUT(0x3A)
+	for _, tc := range []struct {
+		temp int32
+		want *il0373.LUT
+	}{
+		{-5000, &cold},
+		{9875, &cold},
+		{10000, &warm},
+		{25000, &warm},
+		{60000, nil},
+	} {
+		e, d := newPanel(t)
+		d.LUTs = []il0373.TemperatureLUT{
+			{Range: il0373.TemperatureRange{Min: -20000, Max: 9999}, LUT: cold},
+			{Range: il0373.TemperatureRange{Min: 10000, Max: 50000}, LUT: warm},
+		}
+		e.Temperature = tc.temp
+		if err := d.Display(); err != nil {
+			t.Fatal(err)
+		}
+		checkViolations(t, e)
+		if d.LastTemperature != tc.temp {
+			t.Errorf("%d: LastTemperature = %d", tc.temp, d.LastTemperature)
+		}
+
+		regEn := len(e.PanelSetting) > 0 && e.PanelSetting[0]&(1<<5) != 0
+		if tc.want == nil {
+			if regEn || len(e.LUTs) != 0 {
+				t.Errorf("%d: LUT registers used outside every range, panel setting % x", tc.temp, e.PanelSetting)
+			}
+			continue
+		}
+		if !regEn {
+			t.Errorf("%d: panel setting % x doesn't select the LUT registers", tc.temp, e.PanelSetting)
+		}
+		for cmd, want := range map[byte][]byte{
+			il0373.IL0373_LUT1:  tc.want.VCOM,
+			il0373.IL0373_LUTWW: tc.want.WW,
+			il0373.IL0373_LUTBB: tc.want.BB,
+		} {
+			if !bytes.Equal(e.LUTs[cmd], want) {
+				t.Errorf("%d: LUT 0x%02x = % x", tc.temp, cmd, e.LUTs[cmd])
+			}
+		}
+		if _, ok := e.LUTs[il0373.IL0373_LUTR0]; ok {
+			t.Errorf("%d: unset red LUT uploaded", tc.temp)
+		}
+	}
+}
+
+func TestTemperatureRefuse(t *testing.T) {
+	t.Parallel()
+	e, d := newPanel(t)
+	d.TemperaturePolicy = il0373.TemperatureRefuse
+	e.Temperature = -5000
+	if err := d.Display(); !errors.Is(err, il0373.ErrTemperature) {
+		t.Fatalf("Display at -5C: err = %v", err)
+	}
+	checkViolations(t, e)
+	if e.PoweredOn {
+		t.Error("panel left powered on after refusing to refresh")
+	}
+	if e.Refreshes != 0 {
+		t.Errorf("%d refreshes", e.Refreshes)
+	}
+
+	e.Temperature = 20000
+	if err := d.Display(); err != nil {
+		t.Fatal(err)
+	}
+	if e.Refreshes != 1 {
+		t.Errorf("%d refreshes back in range, want 1", e.Refreshes)
+	}
+}
+
+func TestTemperatureWarn(t *testing.T) {
+	t.Parallel()
+	e, d := newPanel(t)
+	d.TemperaturePolicy = il0373.TemperatureWarn
+	e.Temperature = 60000
+	if err := d.Display(); err != nil {
+		t.Fatal(err)
+	}
+	checkViolations(t, e)
+	if e.Refreshes != 1 {
+		t.Errorf("%d refreshes, want 1", e.Refreshes)
+	}
+	if d.LastTemperature != 60000 {
+		t.Errorf("LastTemperature = %d", d.LastTemperature)
+	}
+}
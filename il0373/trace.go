@@ -0,0 +1,290 @@
+package il0373
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/davidadeleon/gophercon2022Badge/epd"
+	"tinygo.org/x/drivers"
+)
+
+// Kinds of trace events
+const (
+	TracePin  = "pin"  // an output pin was driven, Level is set
+	TraceRead = "read" // an input pin read back a new level, Level is set
+	TraceCmd  = "cmd"  // bytes clocked out with DC low
+	TraceData = "data" // bytes clocked out with DC high
+	TraceSRAM = "sram" // bytes clocked out to the SRAM with SRAM_CS low
+)
+
+var ErrBadTrace = errors.New("il0373: malformed trace line")
+
+// Type TraceEvent is one line of a trace. Traces are plain text, one event per
+// line:
+//
+//	<microseconds since start> pin <name> <0|1>
+//	<microseconds since start> read <name> <0|1>
+//	<microseconds since start> cmd <hex bytes>
+//	<microseconds since start> data <hex bytes>
+//	<microseconds since start> sram <hex bytes>
+type TraceEvent struct {
+	At    time.Duration
+	Kind  string
+	Pin   string
+	Level bool
+	Data  []byte
+}
+
+func (e TraceEvent) String() string {
+	var b strings.Builder
+	b.WriteString(strconv.FormatInt(e.At.Microseconds(), 10))
+	b.WriteByte(' ')
+	b.WriteString(e.Kind)
+	switch e.Kind {
+	case TracePin, TraceRead:
+		b.WriteByte(' ')
+		b.WriteString(e.Pin)
+		if e.Level {
+			b.WriteString(" 1")
+		} else {
+			b.WriteString(" 0")
+		}
+	default:
+		b.WriteByte(' ')
+		b.WriteString(hex.EncodeToString(e.Data))
+	}
+	return b.String()
+}
+
+// Func ParseTraceEvent parses a line written by a Tracer
+func ParseTraceEvent(line string) (TraceEvent, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 3 {
+		return TraceEvent{}, ErrBadTrace
+	}
+	us, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return TraceEvent{}, ErrBadTrace
+	}
+	e := TraceEvent{At: time.Duration(us) * time.Microsecond, Kind: fields[1]}
+
+	switch e.Kind {
+	case TracePin, TraceRead:
+		if len(fields) != 4 || (fields[3] != "0" && fields[3] != "1") {
+			return TraceEvent{}, ErrBadTrace
+		}
+		e.Pin = fields[2]
+		e.Level = fields[3] == "1"
+	case TraceCmd, TraceData, TraceSRAM:
+		if e.Data, err = hex.DecodeString(fields[2]); err != nil {
+			return TraceEvent{}, ErrBadTrace
+		}
+	default:
+		return TraceEvent{}, ErrBadTrace
+	}
+	return e, nil
+}
+
+// Func ReadTrace parses a whole trace, blank lines and lines starting with #
+// are skipped
+func ReadTrace(r io.Reader) ([]TraceEvent, error) {
+	var events []TraceEvent
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), 1<<20)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		e, err := ParseTraceEvent(line)
+		if err != nil {
+			return events, err
+		}
+		events = append(events, e)
+	}
+	return events, scanner.Err()
+}
+
+// Type Tracer records everything a Device does on its bus and pins. Wrap a
+// device with Device.Trace before Initialize.
+type Tracer struct {
+	w     io.Writer
+	start time.Time
+	dc    bool
+	sram  bool
+	err   error
+}
+
+// Func NewTracer returns a Tracer writing events to w
+func NewTracer(w io.Writer) *Tracer {
+	return &Tracer{w: w, start: time.Now()}
+}
+
+// Func Err returns the first error hit writing the trace
+func (t *Tracer) Err() error {
+	return t.err
+}
+
+func (t *Tracer) record(e TraceEvent) {
+	if t.err != nil {
+		return
+	}
+	e.At = time.Since(t.start)
+	_, t.err = io.WriteString(t.w, e.String()+"\n")
+}
+
+// Func SPI wraps a bus so every transfer is recorded as cmd or data according
+// to the DC pin, which must be wrapped with Pin as "DC". Transfers made while
+// the pin wrapped as "SRAM_CS" is low are recorded as sram.
+func (t *Tracer) SPI(bus drivers.SPI) drivers.SPI {
+	return &tracedSPI{t: t, bus: bus}
+}
+
+// Func Pin wraps a pin so its transitions are recorded under name. Unwired
+// pins are returned unchanged.
+func (t *Tracer) Pin(name string, p epd.Pin) epd.Pin {
+	if !epd.Connected(p) {
+		return p
+	}
+	return &tracedPin{t: t, name: name, pin: p}
+}
+
+type tracedSPI struct {
+	t   *Tracer
+	bus drivers.SPI
+}
+
+func (s *tracedSPI) Tx(w, r []byte) error {
+	kind := TraceCmd
+	if s.t.sram {
+		kind = TraceSRAM
+	} else if s.t.dc {
+		kind = TraceData
+	}
+	if w != nil {
+		s.t.record(TraceEvent{Kind: kind, Data: w})
+	}
+	return s.bus.Tx(w, r)
+}
+
+func (s *tracedSPI) Transfer(b byte) (byte, error) {
+	buf := []byte{b}
+	err := s.Tx(buf, buf)
+	return buf[0], err
+}
+
+type tracedPin struct {
+	t     *Tracer
+	name  string
+	pin   epd.Pin
+	level bool
+	read  bool
+}
+
+func (p *tracedPin) ConfigureMode(mode epd.PinMode) {
+	epd.Configure(p.pin, mode)
+}
+
+func (p *tracedPin) High() {
+	p.set(true)
+	p.pin.High()
+}
+
+func (p *tracedPin) Low() {
+	p.set(false)
+	p.pin.Low()
+}
+
+func (p *tracedPin) set(level bool) {
+	switch p.name {
+	case "DC":
+		p.t.dc = level
+	case "SRAM_CS":
+		p.t.sram = !level
+	}
+	p.t.record(TraceEvent{Kind: TracePin, Pin: p.name, Level: level})
+}
+
+// Func Get records a read only when the level changes, so busy polling
+// doesn't flood the trace
+func (p *tracedPin) Get() bool {
+	level := p.pin.Get()
+	if !p.read || level != p.level {
+		p.t.record(TraceEvent{Kind: TraceRead, Pin: p.name, Level: level})
+	}
+	p.read = true
+	p.level = level
+	return level
+}
+
+// Func Trace wraps the device's bus and pins so everything it does is written
+// to w. It must be called before Initialize.
+func (d *Device) Trace(w io.Writer) *Tracer {
+	t := NewTracer(w)
+	d.SPI = t.SPI(d.SPI)
+	d.CS_PIN = t.Pin("CS", d.CS_PIN)
+	d.DC_PIN = t.Pin("DC", d.DC_PIN)
+	d.SRAM_CS_PIN = t.Pin("SRAM_CS", d.SRAM_CS_PIN)
+	d.RST_PIN = t.Pin("RST", d.RST_PIN)
+	d.BUSY_PIN = t.Pin("BUSY", d.BUSY_PIN)
+	return t
+}
+
+// Type Replayer drives a saved trace into a bus and pins, a real panel on
+// the bench or an emulator. Pins are looked up by the names in the trace,
+// pins that aren't in Pins are skipped.
+type Replayer struct {
+	SPI  drivers.SPI
+	Pins map[string]epd.Pin
+
+	// RealTime sleeps between events to reproduce the recorded timing
+	RealTime bool
+}
+
+// Func Replay plays events in order. Reads are only used for timing.
+func (r *Replayer) Replay(events []TraceEvent) error {
+	start := time.Now()
+	var cmd byte
+	for _, e := range events {
+		if r.RealTime {
+			if wait := e.At - time.Since(start); wait > 0 {
+				time.Sleep(wait)
+			}
+		}
+
+		switch e.Kind {
+		case TracePin:
+			p, ok := r.Pins[e.Pin]
+			if !ok {
+				continue
+			}
+			if e.Level {
+				p.High()
+			} else {
+				p.Low()
+			}
+		case TraceCmd, TraceData, TraceSRAM:
+			if len(e.Data) == 0 {
+				continue
+			}
+			// Data errors are reported against the command they follow,
+			// SRAM errors against the SRAM instruction like SRAM.tx does
+			failed := cmd
+			if e.Kind != TraceData {
+				failed = e.Data[0]
+			}
+			if e.Kind == TraceCmd {
+				cmd = e.Data[len(e.Data)-1]
+			}
+			if err := r.SPI.Tx(e.Data, nil); err != nil {
+				return &BusError{Cmd: failed, Err: err}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package il0373_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/davidadeleon/gophercon2022Badge/il0373"
+	"github.com/davidadeleon/gophercon2022Badge/il0373/il0373test"
+)
+
+func TestTraceReplay(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		name string
+		sram int
+	}{
+		{"ram", 0},
+		{"sram", 32 * 1024},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			e := il0373test.NewEmulator(width, height)
+			if tc.sram > 0 {
+				e.AttachSRAM(tc.sram)
+			}
+			d := e.Device()
+			var trace bytes.Buffer
+			tracer := d.Trace(&trace)
+			if err := d.Initialize(); err != nil {
+				t.Fatal(err)
+			}
+			d.Fill(il0373.WHITE)
+			for x := 0; x < 40; x++ {
+				d.Pixel(x, 7, il0373.BLACK)
+				d.Pixel(x+40, 100, il0373.RED)
+			}
+			if err := d.Display(); err != nil {
+				t.Fatal(err)
+			}
+			if err := tracer.Err(); err != nil {
+				t.Fatal(err)
+			}
+			checkViolations(t, e)
+
+			events, err := il0373.ReadTrace(&trace)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var sramEvents int
+			for _, ev := range events {
+				if ev.Kind == il0373.TraceSRAM {
+					sramEvents++
+				}
+			}
+			if (sramEvents > 0) != (tc.sram > 0) {
+				t.Errorf("%d sram events in the trace", sramEvents)
+			}
+
+			replay := il0373test.NewEmulator(width, height)
+			if tc.sram > 0 {
+				replay.AttachSRAM(tc.sram)
+			}
+			r := &il0373.Replayer{SPI: replay, Pins: replay.Pins()}
+			if err := r.Replay(events); err != nil {
+				t.Fatal(err)
+			}
+			checkViolations(t, replay)
+			if replay.Refreshes != e.Refreshes {
+				t.Errorf("replay refreshed %d times, want %d", replay.Refreshes, e.Refreshes)
+			}
+			if !bytes.Equal(replay.Image().Pix, e.Image().Pix) {
+				t.Error("replayed panel differs from the traced one")
+			}
+			checkPixel(t, replay, 0, 7, black)
+			checkPixel(t, replay, 40, 100, red)
+			checkPixel(t, replay, 40, 7, white)
+		})
+	}
+}
+
+func TestReplayBusError(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		trace string
+		cmd   byte
+	}{
+		{"0 cmd 10\n1 data ff\n2 data ffff\n", 0x10},
+		{"0 cmd 10\n1 data ff\n2 cmd 13\n", 0x13},
+		{"0 cmd 10\n1 data ff\n2 sram 020000\n", 0x02},
+	} {
+		events, err := il0373.ReadTrace(strings.NewReader(tc.trace))
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := &il0373.Replayer{SPI: &il0373test.SPI{Err: errBus, FailAfter: 2}}
+		checkBusError(t, r.Replay(events), tc.cmd)
+	}
+}
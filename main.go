@@ -1,11 +1,13 @@
 package main
 
 import (
+	"context"
+	"image"
 	"image/color"
 	"machine"
-	"reflect"
 
 	"github.com/davidadeleon/gophercon2022Badge/button"
+	"github.com/davidadeleon/gophercon2022Badge/epd"
 	"github.com/davidadeleon/gophercon2022Badge/il0373"
 	"github.com/davidadeleon/gophercon2022Badge/neopixel"
 	"tinygo.org/x/drivers/apds9960"
@@ -20,16 +22,13 @@ const (
 )
 
 var (
-	eDisplay                *il0373.Device
-	neoPixelStickController = neopixel.NeoPixelController{
-		Pin: machine.GPIO26,
-	}
-	sensor      = apds9960.New(machine.I2C1)
-	eController = neopixel.EffectsController{APDS9960: &sensor}
+	eDisplay                epd.Device
+	neoPixelStickController neopixel.NeoPixelController
+	sensor                  = apds9960.New(machine.I2C1)
+	eController             = neopixel.EffectsController{APDS9960: &sensor}
 	// RGBA Colors for Text Printing
-	//white := color.RGBA{0, 0, 0, 255}
 	red   = color.RGBA{255, 0, 0, 255}
-	black = color.RGBA{255, 255, 255, 255}
+	black = color.RGBA{0, 0, 0, 255}
 )
 
 func init() {
@@ -51,6 +50,7 @@ func main() {
 	println("[GC22_Badge] Starting!")
 
 	// Setup NeoPixel Stick and fill witn inital color
+	neoPixelStickController.Controller = neopixel.NewWS2812(machine.GPIO26)
 	neoPixelStickController.Init(8)
 
 	// Setup APDS9960 Sensor
@@ -58,7 +58,8 @@ func main() {
 
 	sensor.EnableGesture()
 
-	// Setup IL0373 ePaper Display
+	// Setup ePaper Display, the rest of the badge only uses epd.Device so
+	// another panel driver can be dropped in here
 	println("[GC22_Badge] Initializing display...")
 	eDisplay = il0373.New(
 		width,
@@ -70,7 +71,9 @@ func main() {
 		machine.NoPin, // RST Pin
 		machine.NoPin, // BUSY Pin
 	)
-	eDisplay.Initialize()
+	if err := eDisplay.Init(); err != nil {
+		println("[GC22_Badge] Failed to initialize display:", err.Error())
+	}
 	eDisplay.SetRotation(0)
 	println("[GC22_Badge] Initialized!")
 
@@ -79,7 +82,7 @@ func main() {
 
 	// Create Effects Controller
 	eController.Init(&neoPixelStickController)
-	eController.Start()
+	eController.Start(context.Background())
 
 	// Create Button Manager
 	buttonManager := button.ButtonManager{
@@ -118,29 +121,37 @@ func main() {
 func NameBadgeDisplay() {
 	// Clear ePaper Display
 	println("[GC22_Badge] Clearing Display")
-	eDisplay.Fill(il0373.WHITE)
-
-	// Lazy fix reversed image
-	for _, row := range qr_code_github_buf {
-		reverseSlice(row)
+	if err := eDisplay.Fill(epd.WHITE); err != nil {
+		println("[GC22_Badge] Failed to clear display:", err.Error())
+		return
 	}
 
 	// Display
 	println("[GC22_Badge] Display...")
-	eDisplay.DisplayImage(width, height, qr_code_github_buf)
+	if err := eDisplay.DisplayImage(0, 0, qrImage(qr_code_github_buf)); err != nil {
+		println("[GC22_Badge] Failed to draw image:", err.Error())
+	}
 	tinyfont.WriteLineRotated(eDisplay, &freemono.Bold18pt7b, 35, 290, "David", black, tinyfont.ROTATION_270)
 	tinyfont.WriteLineRotated(eDisplay, &freemono.Bold18pt7b, 65, 290, "De Leon", black, tinyfont.ROTATION_270)
 	tinyfont.WriteLineRotated(eDisplay, &gophers.Regular58pt, 125, 290, "E", black, tinyfont.ROTATION_270)
 	tinyfont.WriteLineRotated(eDisplay, &freemono.Regular9pt7b, 105, 245, "Built using", red, tinyfont.ROTATION_270)
 	tinyfont.WriteLineRotated(eDisplay, &freemono.Bold9pt7b, 120, 245, "TinyGo!", red, tinyfont.ROTATION_270)
-	eDisplay.Display()
-	eDisplay.PowerDown()
 
-	println("[GC22_Badge] Done!")
+	// Refresh in the background so the buttons stay responsive
+	done := epd.DisplayAsync(eDisplay)
+	go func() {
+		if err := <-done; err != nil {
+			println("[GC22_Badge] Display failed:", err.Error())
+		}
+		if err := eDisplay.Sleep(); err != nil {
+			println("[GC22_Badge] Sleep failed:", err.Error())
+		}
+		println("[GC22_Badge] Done!")
+	}()
 }
 
 func ChangeColor() {
-	eController.ColorCh <- struct{}{}
+	eController.NextColor()
 }
 
 func ClearPixelBar() {
@@ -160,10 +171,22 @@ func EffectRGB(stopCh chan struct{}) {
 }
 */
 
-func reverseSlice(s interface{}) {
-	n := reflect.ValueOf(s).Len()
-	swap := reflect.Swapper(s)
-	for i, j := 0, n-1; i < j; i, j = i+1, j-1 {
-		swap(i, j)
+// Type qrImage adapts the QR code, stored as one slice per column running
+// bottom to top with 0 for black, to an image.Image
+type qrImage [][]byte
+
+func (q qrImage) ColorModel() color.Model {
+	return color.GrayModel
+}
+
+func (q qrImage) Bounds() image.Rectangle {
+	if len(q) == 0 {
+		return image.Rectangle{}
 	}
+	return image.Rect(0, 0, len(q), len(q[0]))
+}
+
+func (q qrImage) At(x, y int) color.Color {
+	column := q[x]
+	return color.Gray{Y: column[len(column)-1-y]}
 }
@@ -0,0 +1,11 @@
+//go:build tinygo
+
+package neopixel
+
+import "tinygo.org/x/drivers/apds9960"
+
+var _ Sensor = (*apds9960.Device)(nil)
+
+// The gesture values are compared straight against ReadGesture's
+var _ = [1]struct{}{}[GestureUp-apds9960.GESTURE_UP+GestureDown-apds9960.GESTURE_DOWN+
+	GestureLeft-apds9960.GESTURE_LEFT+GestureRight-apds9960.GESTURE_RIGHT]
@@ -0,0 +1,227 @@
+package neopixel
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Type Frame is what an effect is given to render one frame
+type Frame struct {
+	// Number counts frame slots from 0 since the effect was started, it
+	// jumps when the scheduler skips frames
+	Number int
+	// Elapsed is the time since the effect was started and Delta the time
+	// since the previous frame
+	Elapsed time.Duration
+	Delta   time.Duration
+	// Color is the palette's selected color
+	Color   RGBW
+	Palette *Palette
+}
+
+// Type Param is one tunable setting of an effect, for menus and logging
+type Param struct {
+	Name  string
+	Value interface{}
+}
+
+// Type Effect is an LED animation. Render draws frame f into pixels, which
+// hold the previous frame (all off for frame 0). Effects work out where they
+// are from the frame rather than sleeping, so they run at whatever rate the
+// controller drives them and can be rendered without hardware.
+type Effect interface {
+	Name() string
+	Params() []Param
+	Render(f Frame, pixels []RGBW)
+}
+
+// Type EffectsController drives one Effect at a time on a Segment. Give each
+// segment its own controller to run different effects side by side.
+type EffectsController struct {
+	Effects     []Effect
+	EffectIndex int
+	// APDS9960, if set before Start, adds the proximity and gesture effects
+	APDS9960 Sensor
+
+	// Palette is the colors effects draw with, DefaultPalette if nil at Init
+	Palette *Palette
+
+	// FPS is the frame rate, DefaultFPS when zero
+	FPS int
+
+	// Transition blends effects into each other over TransitionTime when
+	// they change. A nil Transition or zero TransitionTime cuts straight
+	// over, Init picks a crossfade if neither is set.
+	Transition     Transition
+	TransitionTime time.Duration
+
+	segment   *Segment
+	scheduler Scheduler
+
+	// mu guards the effect selection, which buttons change while run renders
+	mu      sync.Mutex
+	running bool
+	restart bool
+}
+
+// Func Init runs effects on the whole of a strip
+func (e *EffectsController) Init(n *NeoPixelController) {
+	e.InitSegment(Whole("all", n))
+}
+
+// Func InitSegment runs effects on a segment
+func (e *EffectsController) InitSegment(s *Segment) {
+	e.segment = s
+	e.EffectIndex = 0
+	if e.Palette == nil {
+		e.Palette = DefaultPalette()
+	}
+	if e.Transition == nil && e.TransitionTime == 0 {
+		e.Transition = &Crossfade{}
+		e.TransitionTime = DefaultTransitionTime
+	}
+	e.Register(
+		&Static{},
+		&CycleColors{Period: time.Second},
+		&KnightRider{Step: 50 * time.Millisecond},
+		&Glow{Steps: 64, Step: 10 * time.Millisecond},
+	)
+	e.Register(Library()...)
+}
+
+// Func Register adds effects to the end of the rotation
+func (e *EffectsController) Register(effects ...Effect) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.Effects = append(e.Effects, effects...)
+}
+
+// Func Start runs the first effect in a goroutine until ctx is cancelled,
+// when the strip is switched off
+func (e *EffectsController) Start(ctx context.Context) {
+	if e.APDS9960 != nil {
+		e.Register(
+			&ProximitySense{Sensor: e.APDS9960},
+			&GestureSense{Sensor: e.APDS9960, Step: 50 * time.Millisecond},
+		)
+	}
+
+	e.mu.Lock()
+	e.running = true
+	e.restart = true
+	e.mu.Unlock()
+
+	go e.run(ctx)
+}
+
+// Func ChangeEffect switches to the next effect, resuming if stopped
+func (e *EffectsController) ChangeEffect() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.Effects) == 0 {
+		return
+	}
+	e.EffectIndex = (e.EffectIndex + 1) % len(e.Effects)
+	e.running = true
+	e.restart = true
+}
+
+// Func StopEffects blanks the strip until the next ChangeEffect
+func (e *EffectsController) StopEffects() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.running = false
+}
+
+// Func NextColor selects the next palette color
+func (e *EffectsController) NextColor() {
+	e.Palette.Next()
+}
+
+// Func PreviousColor selects the previous palette color
+func (e *EffectsController) PreviousColor() {
+	e.Palette.Previous()
+}
+
+// Func FrameStats returns the measured frame timings
+func (e *EffectsController) FrameStats() SchedulerStats {
+	return e.scheduler.Stats()
+}
+
+// Func Current returns the effect being shown, nil when stopped
+func (e *EffectsController) Current() Effect {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.running || len(e.Effects) == 0 {
+		return nil
+	}
+	return e.Effects[e.EffectIndex]
+}
+
+func (e *EffectsController) run(ctx context.Context) {
+	n := e.segment.Len()
+	cur := &track{pixels: make([]RGBW, n)}
+	prev := &track{pixels: make([]RGBW, n)}
+	out := make([]RGBW, n)
+	fading := false
+	var fadeStart time.Duration
+	blank := false
+
+	e.scheduler.FPS = e.FPS
+	e.scheduler.Run(ctx, func(tick Tick) {
+		e.mu.Lock()
+		if !e.running || len(e.Effects) == 0 {
+			e.mu.Unlock()
+			if !blank {
+				e.segment.Clear()
+				cur.effect = nil
+				fading = false
+				blank = true
+			}
+			return
+		}
+		if e.restart {
+			effect := e.Effects[e.EffectIndex]
+			e.restart = false
+			// Coming back from blank fades in from black
+			if e.Transition != nil && e.TransitionTime > 0 && effect != cur.effect {
+				cur, prev = prev, cur
+				if blank {
+					prev.begin(nil, tick)
+				}
+				fading = true
+				fadeStart = tick.Elapsed
+			}
+			cur.begin(effect, tick)
+		}
+		transition, duration := e.Transition, e.TransitionTime
+		e.mu.Unlock()
+		blank = false
+
+		cur.render(tick, e.Palette)
+		pixels := cur.pixels
+		if fading {
+			var progress time.Duration = 255
+			if transition != nil && duration > 0 {
+				progress = (tick.Elapsed - fadeStart) * 255 / duration
+			}
+			if progress >= 255 {
+				fading = false
+				prev.effect = nil
+			} else {
+				prev.render(tick, e.Palette)
+				transition.Blend(prev.pixels, cur.pixels, uint8(progress), out)
+				pixels = out
+			}
+		}
+		e.segment.Write(pixels)
+	})
+	e.segment.Clear()
+}
+
+func fill(pixels []RGBW, c RGBW) {
+	for i := range pixels {
+		pixels[i] = c
+	}
+}
@@ -0,0 +1,207 @@
+package neopixel
+
+import (
+	"time"
+)
+
+// Type Static shows the selected color on every pixel
+type Static struct{}
+
+func (s *Static) Name() string    { return "Static" }
+func (s *Static) Params() []Param { return nil }
+
+func (s *Static) Render(f Frame, pixels []RGBW) {
+	fill(pixels, f.Color)
+}
+
+// Type CycleColors steps through the palette, one color per Period, starting
+// from the selected one
+type CycleColors struct {
+	Period time.Duration
+}
+
+func (c *CycleColors) Name() string { return "Cycle Colors" }
+
+func (c *CycleColors) Params() []Param {
+	return []Param{{"Period", c.Period}}
+}
+
+func (c *CycleColors) Render(f Frame, pixels []RGBW) {
+	if f.Palette == nil || c.Period <= 0 {
+		fill(pixels, f.Color)
+		return
+	}
+	fill(pixels, f.Palette.At(f.Palette.Index()+int(f.Elapsed/c.Period)))
+}
+
+// Type KnightRider bounces a single pixel end to end, moving one pixel per
+// Step
+type KnightRider struct {
+	Step time.Duration
+}
+
+func (k *KnightRider) Name() string { return "Hasselhoff" }
+
+func (k *KnightRider) Params() []Param {
+	return []Param{{"Step", k.Step}}
+}
+
+func (k *KnightRider) Render(f Frame, pixels []RGBW) {
+	fill(pixels, off)
+	if len(pixels) == 0 || k.Step <= 0 {
+		return
+	}
+	pixels[bounce(int(f.Elapsed/k.Step), len(pixels))] = f.Color
+}
+
+// Func bounce maps a step count onto 0..n-1 and back again
+func bounce(step, n int) int {
+	if n < 2 {
+		return 0
+	}
+	period := 2 * (n - 1)
+	pos := step % period
+	if pos >= n {
+		pos = period - pos
+	}
+	return pos
+}
+
+// Type Glow fades the selected color up and down in Steps levels, one level
+// per Step
+type Glow struct {
+	Steps int
+	Step  time.Duration
+}
+
+func (g *Glow) Name() string { return "Glow" }
+
+func (g *Glow) Params() []Param {
+	return []Param{{"Steps", g.Steps}, {"Step", g.Step}}
+}
+
+func (g *Glow) Render(f Frame, pixels []RGBW) {
+	if g.Steps <= 0 || g.Step <= 0 {
+		fill(pixels, f.Color)
+		return
+	}
+	level := bounce(int(f.Elapsed/g.Step), g.Steps+1)
+	fill(pixels, Lerp(off, f.Color, uint8(level*255/g.Steps)))
+}
+
+// Type ProximitySensor reads how close something is, 0 near to 255 out of
+// range. *apds9960.Device is one.
+type ProximitySensor interface {
+	EnableProximity()
+	ProximityAvailable() bool
+	ReadProximity() int32
+}
+
+// Hand swipes reported by a GestureSensor, the same values as the apds9960
+// package's GESTURE_ constants
+const (
+	GestureNone = iota
+	GestureUp
+	GestureDown
+	GestureLeft
+	GestureRight
+)
+
+// Type GestureSensor reads hand swipes. *apds9960.Device is one.
+type GestureSensor interface {
+	EnableGesture()
+	Setsensitivity(s uint8)
+	SetGains(proximityGain, gestureGain, colorGain uint8)
+	LEDBoost(percent uint16)
+	GestureAvailable() bool
+	ReadGesture() int32
+}
+
+// Type Sensor is both, like the badge's APDS9960
+type Sensor interface {
+	ProximitySensor
+	GestureSensor
+}
+
+// Type ProximitySense lights more of the strip the closer something is to
+// the sensor
+type ProximitySense struct {
+	Sensor ProximitySensor
+}
+
+func (p *ProximitySense) Name() string    { return "Proximity" }
+func (p *ProximitySense) Params() []Param { return nil }
+
+func (p *ProximitySense) Render(f Frame, pixels []RGBW) {
+	if f.Number == 0 {
+		p.Sensor.EnableProximity()
+	}
+	if !p.Sensor.ProximityAvailable() {
+		return
+	}
+	proximity := p.Sensor.ReadProximity()
+	if proximity == 255 {
+		// Too Far to Measure
+		return
+	}
+	end := int(proximity) * len(pixels) / 256
+	for i := range pixels {
+		if i >= end {
+			pixels[i] = f.Color
+		} else {
+			pixels[i] = off
+		}
+	}
+}
+
+// Type GestureSense sweeps a pixel across the strip in the direction of a
+// hand swipe, one pixel per Step
+type GestureSense struct {
+	Sensor GestureSensor
+	Step   time.Duration
+
+	sweeping bool
+	forward  bool
+	start    time.Duration
+}
+
+func (g *GestureSense) Name() string { return "Gesture" }
+
+func (g *GestureSense) Params() []Param {
+	return []Param{{"Step", g.Step}}
+}
+
+func (g *GestureSense) Render(f Frame, pixels []RGBW) {
+	if f.Number == 0 {
+		g.Sensor.EnableGesture()
+		g.Sensor.Setsensitivity(100)
+		g.Sensor.SetGains(1, 4, 4)
+		g.Sensor.LEDBoost(300)
+		g.sweeping = false
+	}
+
+	if !g.sweeping && g.Sensor.GestureAvailable() {
+		// The sensor is mounted rotated, up and left both sweep towards 0
+		switch g.Sensor.ReadGesture() {
+		case GestureUp, GestureLeft:
+			g.sweeping, g.forward = true, false
+		case GestureDown, GestureRight:
+			g.sweeping, g.forward = true, true
+		}
+		g.start = f.Elapsed
+	}
+
+	fill(pixels, off)
+	if !g.sweeping || g.Step <= 0 {
+		return
+	}
+	i := int((f.Elapsed - g.start) / g.Step)
+	if i >= len(pixels) {
+		g.sweeping = false
+		return
+	}
+	if !g.forward {
+		i = len(pixels) - 1 - i
+	}
+	pixels[i] = f.Color
+}
@@ -0,0 +1,175 @@
+package neopixel_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/davidadeleon/gophercon2022Badge/neopixel"
+)
+
+var (
+	off   = neopixel.RGBW{}
+	color = neopixel.RGBW{R: 200, G: 100}
+)
+
+// Func render draws effect at elapsed into n pixels that start off
+func render(effect neopixel.Effect, number int, elapsed time.Duration, n int) []neopixel.RGBW {
+	pixels := make([]neopixel.RGBW, n)
+	effect.Render(neopixel.Frame{Number: number, Elapsed: elapsed, Color: color, Palette: neopixel.DefaultPalette()}, pixels)
+	return pixels
+}
+
+// Func lit returns the pixels that are on, and checks they're all c
+func lit(t *testing.T, pixels []neopixel.RGBW, c neopixel.RGBW) []int {
+	t.Helper()
+	var on []int
+	for i, p := range pixels {
+		if p == off {
+			continue
+		}
+		if p != c {
+			t.Errorf("pixel %d = %v, want %v", i, p, c)
+		}
+		on = append(on, i)
+	}
+	return on
+}
+
+func equal(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestStatic(t *testing.T) {
+	if on := lit(t, render(&neopixel.Static{}, 5, time.Second, 4), color); len(on) != 4 {
+		t.Errorf("lit %v, want all", on)
+	}
+}
+
+func TestCycleColors(t *testing.T) {
+	p := neopixel.DefaultPalette()
+	c := &neopixel.CycleColors{Period: time.Second}
+	for _, tc := range []struct {
+		elapsed time.Duration
+		want    int
+	}{{0, 0}, {999 * time.Millisecond, 0}, {time.Second, 1}, {2500 * time.Millisecond, 2}, {7 * time.Second, 0}} {
+		pixels := render(c, 1, tc.elapsed, 3)
+		lit(t, pixels, p.At(tc.want))
+	}
+}
+
+func TestKnightRider(t *testing.T) {
+	k := &neopixel.KnightRider{Step: 10 * time.Millisecond}
+	for _, tc := range []struct {
+		elapsed time.Duration
+		want    int
+	}{{0, 0}, {10 * time.Millisecond, 1}, {35 * time.Millisecond, 3}, {40 * time.Millisecond, 2}, {60 * time.Millisecond, 0}, {70 * time.Millisecond, 1}} {
+		if on := lit(t, render(k, 1, tc.elapsed, 4), color); !equal(on, []int{tc.want}) {
+			t.Errorf("at %v lit %v, want %d", tc.elapsed, on, tc.want)
+		}
+	}
+}
+
+func TestGlow(t *testing.T) {
+	g := &neopixel.Glow{Steps: 4, Step: 10 * time.Millisecond}
+	for _, tc := range []struct {
+		elapsed time.Duration
+		want    neopixel.RGBW
+	}{
+		{0, off},
+		{20 * time.Millisecond, neopixel.RGBW{R: 99, G: 49}},
+		{40 * time.Millisecond, color},
+		{60 * time.Millisecond, neopixel.RGBW{R: 99, G: 49}},
+		{80 * time.Millisecond, off},
+	} {
+		for i, p := range render(g, 1, tc.elapsed, 2) {
+			if p != tc.want {
+				t.Errorf("at %v pixel %d = %v, want %v", tc.elapsed, i, p, tc.want)
+			}
+		}
+	}
+}
+
+// Type sensor is a fake APDS9960
+type sensor struct {
+	proximity   int32
+	proximityOK bool
+	gestures    []int32
+	enabled     string
+}
+
+func (s *sensor) EnableProximity()             { s.enabled = "proximity" }
+func (s *sensor) ProximityAvailable() bool     { return s.proximityOK }
+func (s *sensor) ReadProximity() int32         { return s.proximity }
+func (s *sensor) EnableGesture()               { s.enabled = "gesture" }
+func (s *sensor) Setsensitivity(uint8)         {}
+func (s *sensor) SetGains(uint8, uint8, uint8) {}
+func (s *sensor) LEDBoost(uint16)              {}
+func (s *sensor) GestureAvailable() bool       { return len(s.gestures) > 0 }
+func (s *sensor) ReadGesture() int32 {
+	g := s.gestures[0]
+	s.gestures = s.gestures[1:]
+	return g
+}
+
+var _ neopixel.Sensor = (*sensor)(nil)
+
+func TestProximitySense(t *testing.T) {
+	s := &sensor{}
+	p := &neopixel.ProximitySense{Sensor: s}
+	if on := lit(t, render(p, 0, 0, 8), color); len(on) != 0 || s.enabled != "proximity" {
+		t.Errorf("frame 0 lit %v, sensor %q", on, s.enabled)
+	}
+
+	for _, tc := range []struct {
+		proximity int32
+		want      []int
+	}{
+		{0, []int{0, 1, 2, 3, 4, 5, 6, 7}},
+		{128, []int{4, 5, 6, 7}},
+		{254, []int{7}},
+		// Out of range leaves the previous frame, here all off
+		{255, nil},
+	} {
+		s.proximity, s.proximityOK = tc.proximity, true
+		if on := lit(t, render(p, 1, 0, 8), color); !equal(on, tc.want) {
+			t.Errorf("proximity %d lit %v, want %v", tc.proximity, on, tc.want)
+		}
+	}
+}
+
+func TestGestureSense(t *testing.T) {
+	step := 10 * time.Millisecond
+	for _, tc := range []struct {
+		gesture int32
+		at      []time.Duration
+		want    []int
+	}{
+		{neopixel.GestureRight, []time.Duration{0, 20 * time.Millisecond, 70 * time.Millisecond}, []int{0, 2, 7}},
+		{neopixel.GestureDown, []time.Duration{0, 30 * time.Millisecond}, []int{0, 3}},
+		{neopixel.GestureLeft, []time.Duration{0, 20 * time.Millisecond}, []int{7, 5}},
+		{neopixel.GestureUp, []time.Duration{0, 70 * time.Millisecond}, []int{7, 0}},
+	} {
+		s := &sensor{gestures: []int32{tc.gesture}}
+		g := &neopixel.GestureSense{Sensor: s, Step: step}
+		for i, at := range tc.at {
+			if on := lit(t, render(g, i, at, 8), color); !equal(on, []int{tc.want[i]}) {
+				t.Errorf("gesture %d at %v lit %v, want %d", tc.gesture, at, on, tc.want[i])
+			}
+		}
+		// Off the end, the sweep is over until the next swipe
+		if on := lit(t, render(g, 10, 80*time.Millisecond, 8), color); len(on) != 0 {
+			t.Errorf("gesture %d lit %v after the sweep", tc.gesture, on)
+		}
+		if s.enabled != "gesture" {
+			t.Error("gesture sensing not enabled on frame 0")
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package neopixel
+
+import "math"
+
+// Default output settings. The palette used to be capped at 0x64 by hand,
+// DefaultBrightness keeps full range colors at that level.
+const (
+	DefaultBrightness = 0x64
+	DefaultGamma      = 2.8
+)
+
+// Type GammaTable maps a channel value to its perceptually corrected output
+type GammaTable [256]byte
+
+// Func NewGammaTable builds the table for out = 255 * (in/255)^gamma, a gamma
+// of 1 is linear
+func NewGammaTable(gamma float64) *GammaTable {
+	var t GammaTable
+	for i := range t {
+		t[i] = byte(math.Pow(float64(i)/255, gamma)*255 + 0.5)
+	}
+	return &t
+}
+
+var defaultGammaTable = NewGammaTable(DefaultGamma)
+
+// Func SetBrightness sets the global brightness, 0 (off) to 255 (full),
+// applied to every pixel when it's written to the strip
+func (n *NeoPixelController) SetBrightness(brightness uint8) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.Brightness = brightness
+}
+
+// Func SetGamma replaces the gamma correction table, nil disables correction
+func (n *NeoPixelController) SetGamma(table *GammaTable) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.Gamma = table
+}
+
+// Func correct applies gamma then brightness, so dimming is linear in light
+// output rather than in channel values
+func (n *NeoPixelController) correct(c RGBW) RGBW {
+	channel := func(v byte) byte {
+		if n.Gamma != nil {
+			v = n.Gamma[v]
+		}
+		return byte((uint16(v)*uint16(n.Brightness) + 127) / 255)
+	}
+	return RGBW{channel(c.R), channel(c.G), channel(c.B), channel(c.W)}
+}
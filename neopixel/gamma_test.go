@@ -0,0 +1,49 @@
+package neopixel_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/davidadeleon/gophercon2022Badge/neopixel"
+)
+
+func TestGammaTable(t *testing.T) {
+	for _, gamma := range []float64{1, 2.2, neopixel.DefaultGamma} {
+		table := neopixel.NewGammaTable(gamma)
+		if table[0] != 0 || table[255] != 255 {
+			t.Errorf("gamma %v: ends at %d and %d", gamma, table[0], table[255])
+		}
+		for i := 1; i < len(table); i++ {
+			if table[i] < table[i-1] {
+				t.Errorf("gamma %v: %d maps to %d after %d", gamma, i, table[i], table[i-1])
+				break
+			}
+			if gamma == 1 && table[i] != byte(i) {
+				t.Errorf("gamma 1: %d maps to %d", i, table[i])
+				break
+			}
+		}
+	}
+}
+
+func TestDefaultCorrection(t *testing.T) {
+	s := &strip{}
+	n := &neopixel.NeoPixelController{Controller: s, Order: neopixel.OrderRGB}
+	n.Init(1)
+	for _, tc := range []struct {
+		in   byte
+		want byte
+	}{
+		{0x00, 0},
+		// Full on is sent at DefaultBrightness
+		{0xFF, neopixel.DefaultBrightness},
+		// Gamma dims the middle more than brightness alone would
+		{0x80, 15},
+		{0x01, 0},
+	} {
+		n.Fill(neopixel.RGBW{R: tc.in, G: tc.in, B: tc.in})
+		if want := []byte{tc.want, tc.want, tc.want}; !bytes.Equal(s.last(), want) {
+			t.Errorf("0x%02x sent as % x, want % x", tc.in, s.last(), want)
+		}
+	}
+}
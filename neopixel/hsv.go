@@ -0,0 +1,198 @@
+package neopixel
+
+// Hue is a full turn of the color wheel in 16 bits, 0 is red, HueGreen and
+// HueBlue are a third and two thirds of the way round. Integer math keeps the
+// conversions cheap on microcontrollers without an FPU.
+const (
+	HueRed   uint16 = 0
+	HueGreen uint16 = 65536 / 3
+	HueBlue  uint16 = 65536 * 2 / 3
+)
+
+// Type HSV is a hue, saturation, value color
+type HSV struct {
+	H uint16
+	S uint8
+	V uint8
+}
+
+// Type HSL is a hue, saturation, lightness color
+type HSL struct {
+	H uint16
+	S uint8
+	L uint8
+}
+
+// Func RGBW converts to RGB, the white channel is left off, see ExtractWhite
+func (c HSV) RGBW() RGBW {
+	// Map the hue onto 6 sectors of 255 steps
+	hue := (uint32(c.H)*1530 + 32768) / 65536
+	var r, g, b uint32
+	switch {
+	case hue < 255:
+		r, g, b = 255, hue, 0
+	case hue < 510:
+		r, g, b = 510-hue, 255, 0
+	case hue < 765:
+		r, g, b = 0, 255, hue-510
+	case hue < 1020:
+		r, g, b = 0, 1020-hue, 255
+	case hue < 1275:
+		r, g, b = hue-1020, 0, 255
+	case hue < 1530:
+		r, g, b = 255, 0, 1530-hue
+	default:
+		r, g, b = 255, 0, 0
+	}
+
+	s1 := uint32(c.S) + 1
+	s2 := 255 - uint32(c.S)
+	v1 := uint32(c.V) + 1
+	scale := func(x uint32) byte {
+		return byte(((x*s1)>>8 + s2) * v1 >> 8)
+	}
+	return RGBW{R: scale(r), G: scale(g), B: scale(b)}
+}
+
+// Func HSV converts to hue, saturation, value. The white channel is folded
+// into R, G and B first.
+func (c RGBW) HSV() HSV {
+	r, g, b := int32(addSat(c.R, c.W)), int32(addSat(c.G, c.W)), int32(addSat(c.B, c.W))
+	max, min := r, r
+	for _, v := range []int32{g, b} {
+		if v > max {
+			max = v
+		}
+		if v < min {
+			min = v
+		}
+	}
+	delta := max - min
+	if max == 0 || delta == 0 {
+		return HSV{V: uint8(max)}
+	}
+
+	// Hue in the same 1530 step space RGBW uses
+	var h int32
+	switch max {
+	case r:
+		h = (g - b) * 255 / delta
+		if h < 0 {
+			h += 1530
+		}
+	case g:
+		h = 510 + (b-r)*255/delta
+	default:
+		h = 1020 + (r-g)*255/delta
+	}
+	return HSV{
+		H: uint16((h*65536 + 765) / 1530),
+		S: uint8(delta * 255 / max),
+		V: uint8(max),
+	}
+}
+
+// Func HSV converts to hue, saturation, value
+func (c HSL) HSV() HSV {
+	l := uint32(c.L)
+	m := l
+	if 255-l < m {
+		m = 255 - l
+	}
+	v := l + (uint32(c.S)*m+127)/255
+	if v == 0 {
+		return HSV{H: c.H}
+	}
+	return HSV{H: c.H, S: uint8((510*(v-l) + v/2) / v), V: uint8(v)}
+}
+
+// Func HSL converts to hue, saturation, lightness
+func (c HSV) HSL() HSL {
+	v := uint32(c.V)
+	l := v - (v*uint32(c.S)+255)/510
+	m := l
+	if 255-l < m {
+		m = 255 - l
+	}
+	if m == 0 {
+		return HSL{H: c.H, L: uint8(l)}
+	}
+	s := (255*(v-l) + m/2) / m
+	if s > 255 {
+		s = 255
+	}
+	return HSL{H: c.H, S: uint8(s), L: uint8(l)}
+}
+
+// Func RGBW converts to RGB, the white channel is left off, see ExtractWhite
+func (c HSL) RGBW() RGBW {
+	return c.HSV().RGBW()
+}
+
+// Func HSL converts to hue, saturation, lightness. The white channel is folded
+// into R, G and B first.
+func (c RGBW) HSL() HSL {
+	return c.HSV().HSL()
+}
+
+// Func ExtractWhite moves the grey part of a color, min(R, G, B), onto the
+// white channel for RGBW strips. The white LED is brighter and truer than
+// mixing all three.
+func (c RGBW) ExtractWhite() RGBW {
+	m := c.R
+	if c.G < m {
+		m = c.G
+	}
+	if c.B < m {
+		m = c.B
+	}
+	return RGBW{R: c.R - m, G: c.G - m, B: c.B - m, W: addSat(c.W, m)}
+}
+
+// Func Wheel returns a fully saturated color going red, green, blue and back
+// to red as pos goes from 0 to 255
+func Wheel(pos byte) RGBW {
+	pos = 255 - pos
+	switch {
+	case pos < 85:
+		return RGBW{R: 255 - pos*3, B: pos * 3}
+	case pos < 170:
+		pos -= 85
+		return RGBW{G: pos * 3, B: 255 - pos*3}
+	default:
+		pos -= 170
+		return RGBW{R: pos * 3, G: 255 - pos*3}
+	}
+}
+
+// Func Lerp blends a to b channel by channel, t of 0 is a and 255 is b
+func Lerp(a, b RGBW, t uint8) RGBW {
+	return RGBW{
+		R: lerp(a.R, b.R, t),
+		G: lerp(a.G, b.G, t),
+		B: lerp(a.B, b.B, t),
+		W: lerp(a.W, b.W, t),
+	}
+}
+
+// Func LerpHSV blends a to b in HSV space, taking the short way round the
+// hue circle, t of 0 is a and 255 is b
+func LerpHSV(a, b HSV, t uint8) HSV {
+	diff := int32(int16(b.H - a.H))
+	return HSV{
+		H: a.H + uint16(diff*int32(t)/255),
+		S: lerp(a.S, b.S, t),
+		V: lerp(a.V, b.V, t),
+	}
+}
+
+func lerp(a, b, t uint8) uint8 {
+	return uint8(int32(a) + (int32(b)-int32(a))*int32(t)/255)
+}
+
+func addSat(a, b uint8) uint8 {
+	if s := uint16(a) + uint16(b); s < 255 {
+		return uint8(s)
+	}
+	return 255
+}
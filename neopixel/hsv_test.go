@@ -0,0 +1,173 @@
+package neopixel_test
+
+import (
+	"testing"
+
+	"github.com/davidadeleon/gophercon2022Badge/neopixel"
+)
+
+func near(a, b, tolerance uint8) bool {
+	if a > b {
+		a, b = b, a
+	}
+	return b-a <= tolerance
+}
+
+func nearRGBW(a, b neopixel.RGBW, tolerance uint8) bool {
+	return near(a.R, b.R, tolerance) && near(a.G, b.G, tolerance) && near(a.B, b.B, tolerance) && near(a.W, b.W, tolerance)
+}
+
+// Func hueDistance is the distance round the wheel between two hues
+func hueDistance(a, b uint16) uint16 {
+	d := a - b
+	if d > 32768 {
+		d = -d
+	}
+	return d
+}
+
+func TestHSVToRGBW(t *testing.T) {
+	for _, tc := range []struct {
+		hsv  neopixel.HSV
+		want neopixel.RGBW
+	}{
+		{neopixel.HSV{H: neopixel.HueRed, S: 255, V: 255}, neopixel.RGBW{R: 255}},
+		{neopixel.HSV{H: neopixel.HueGreen, S: 255, V: 255}, neopixel.RGBW{G: 255}},
+		{neopixel.HSV{H: neopixel.HueBlue, S: 255, V: 255}, neopixel.RGBW{B: 255}},
+		{neopixel.HSV{H: neopixel.HueGreen / 2, S: 255, V: 255}, neopixel.RGBW{R: 255, G: 255}},
+		{neopixel.HSV{H: 12345, S: 0, V: 255}, neopixel.RGBW{R: 255, G: 255, B: 255}},
+		{neopixel.HSV{H: 12345, S: 255, V: 0}, neopixel.RGBW{}},
+	} {
+		if got := tc.hsv.RGBW(); !nearRGBW(got, tc.want, 1) {
+			t.Errorf("%+v.RGBW() = %+v, want %+v", tc.hsv, got, tc.want)
+		}
+	}
+}
+
+func TestRGBWToHSV(t *testing.T) {
+	for _, tc := range []struct {
+		rgbw neopixel.RGBW
+		want neopixel.HSV
+	}{
+		{neopixel.RGBW{R: 255}, neopixel.HSV{H: neopixel.HueRed, S: 255, V: 255}},
+		{neopixel.RGBW{G: 255}, neopixel.HSV{H: neopixel.HueGreen, S: 255, V: 255}},
+		{neopixel.RGBW{B: 128}, neopixel.HSV{H: neopixel.HueBlue, S: 255, V: 128}},
+		{neopixel.RGBW{R: 100, G: 100, B: 100}, neopixel.HSV{V: 100}},
+		// W is folded into R, G and B
+		{neopixel.RGBW{W: 200}, neopixel.HSV{V: 200}},
+		{neopixel.RGBW{R: 200, W: 100}, neopixel.HSV{H: neopixel.HueRed, S: 155, V: 255}},
+	} {
+		got := tc.rgbw.HSV()
+		if hueDistance(got.H, tc.want.H) > 256 || !near(got.S, tc.want.S, 1) || got.V != tc.want.V {
+			t.Errorf("%+v.HSV() = %+v, want %+v", tc.rgbw, got, tc.want)
+		}
+	}
+}
+
+func TestHSVRoundTrip(t *testing.T) {
+	for _, c := range []neopixel.RGBW{
+		{}, {R: 255}, {G: 255}, {B: 255}, {R: 255, G: 255, B: 255},
+		{R: 255, G: 128}, {R: 10, G: 200, B: 90}, {R: 128, G: 128, B: 128},
+		{R: 30, G: 60, B: 90}, {R: 250, G: 5, B: 140}, {R: 1, G: 2, B: 3},
+	} {
+		if got := c.HSV().RGBW(); !nearRGBW(got, c, 2) {
+			t.Errorf("%+v through HSV = %+v", c, got)
+		}
+		if got := c.HSL().RGBW(); !nearRGBW(got, c, 3) {
+			t.Errorf("%+v through HSL = %+v", c, got)
+		}
+	}
+}
+
+func TestHSLRoundTrip(t *testing.T) {
+	for _, c := range []neopixel.HSV{
+		{H: 0, S: 255, V: 255}, {H: 20000, S: 128, V: 200}, {H: 40000, S: 30, V: 60},
+		{H: 65535, S: 255, V: 128}, {H: 1000, S: 0, V: 77}, {H: 5000, S: 200, V: 255},
+	} {
+		hsl := c.HSL()
+		got := hsl.HSV()
+		// 8 bit HSL saturation is coarse for dark, washed out colors
+		if got.H != c.H || !near(got.S, c.S, 4) || !near(got.V, c.V, 1) {
+			t.Errorf("%+v through %+v = %+v", c, hsl, got)
+		}
+	}
+
+	for _, tc := range []struct {
+		hsl  neopixel.HSL
+		want neopixel.HSV
+	}{
+		{neopixel.HSL{S: 255, L: 128}, neopixel.HSV{S: 255, V: 255}},
+		{neopixel.HSL{S: 0, L: 90}, neopixel.HSV{S: 0, V: 90}},
+		{neopixel.HSL{S: 255, L: 255}, neopixel.HSV{S: 0, V: 255}},
+		{neopixel.HSL{S: 255, L: 0}, neopixel.HSV{}},
+	} {
+		if got := tc.hsl.HSV(); !near(got.S, tc.want.S, 1) || !near(got.V, tc.want.V, 1) {
+			t.Errorf("%+v.HSV() = %+v, want %+v", tc.hsl, got, tc.want)
+		}
+	}
+}
+
+func TestExtractWhite(t *testing.T) {
+	for _, tc := range []struct {
+		in, want neopixel.RGBW
+	}{
+		{neopixel.RGBW{R: 10, G: 20, B: 30}, neopixel.RGBW{G: 10, B: 20, W: 10}},
+		{neopixel.RGBW{R: 255, G: 255, B: 255}, neopixel.RGBW{W: 255}},
+		{neopixel.RGBW{R: 100, G: 50, W: 7}, neopixel.RGBW{R: 100, G: 50, W: 7}},
+		{neopixel.RGBW{R: 90, G: 80, B: 70, W: 5}, neopixel.RGBW{R: 20, G: 10, W: 75}},
+		// White saturates
+		{neopixel.RGBW{R: 200, G: 200, B: 200, W: 100}, neopixel.RGBW{W: 255}},
+	} {
+		if got := tc.in.ExtractWhite(); got != tc.want {
+			t.Errorf("%+v.ExtractWhite() = %+v, want %+v", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestWheel(t *testing.T) {
+	for _, tc := range []struct {
+		pos  byte
+		want neopixel.RGBW
+	}{
+		{0, neopixel.RGBW{R: 255}},
+		{85, neopixel.RGBW{G: 255}},
+		{170, neopixel.RGBW{B: 255}},
+		{255, neopixel.RGBW{R: 255}},
+		{42, neopixel.RGBW{R: 129, G: 126}},
+	} {
+		if got := neopixel.Wheel(tc.pos); got != tc.want {
+			t.Errorf("Wheel(%d) = %+v, want %+v", tc.pos, got, tc.want)
+		}
+	}
+}
+
+func TestLerpHSV(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		a, b neopixel.HSV
+		t    uint8
+		want neopixel.HSV
+	}{
+		{"start", neopixel.HSV{H: 1000, S: 0, V: 0}, neopixel.HSV{H: 3000, S: 255, V: 255}, 0, neopixel.HSV{H: 1000, S: 0, V: 0}},
+		{"end", neopixel.HSV{H: 1000, S: 0, V: 0}, neopixel.HSV{H: 3000, S: 255, V: 255}, 255, neopixel.HSV{H: 3000, S: 255, V: 255}},
+		{"middle", neopixel.HSV{H: neopixel.HueRed, S: 100, V: 200}, neopixel.HSV{H: neopixel.HueGreen, S: 200, V: 100}, 128, neopixel.HSV{H: 10965, S: 150, V: 150}},
+		// Through red rather than round by cyan
+		{"wraps up", neopixel.HSV{H: 65000}, neopixel.HSV{H: 500}, 128, neopixel.HSV{H: 65520}},
+		{"wraps up to", neopixel.HSV{H: 65000}, neopixel.HSV{H: 500}, 255, neopixel.HSV{H: 500}},
+		{"wraps down", neopixel.HSV{H: 500}, neopixel.HSV{H: 65000}, 128, neopixel.HSV{H: 65516}},
+		{"blue to red", neopixel.HSV{H: neopixel.HueBlue}, neopixel.HSV{H: neopixel.HueRed}, 255, neopixel.HSV{H: neopixel.HueRed}},
+	} {
+		got := neopixel.LerpHSV(tc.a, tc.b, tc.t)
+		if hueDistance(got.H, tc.want.H) > 1 || got.S != tc.want.S || got.V != tc.want.V {
+			t.Errorf("%s: LerpHSV = %+v, want %+v", tc.name, got, tc.want)
+		}
+	}
+
+	// Every step of a wrapping blend stays on the short side of the wheel
+	a, b := neopixel.HSV{H: 60000}, neopixel.HSV{H: 4000}
+	for step := 0; step <= 255; step++ {
+		if h := neopixel.LerpHSV(a, b, uint8(step)).H; h > 4000 && h < 60000 {
+			t.Fatalf("step %d went the long way round, H = %d", step, h)
+		}
+	}
+}
@@ -0,0 +1,402 @@
+package neopixel
+
+import (
+	"math"
+	"time"
+)
+
+// Func Library returns the standard effects with their default settings
+func Library() []Effect {
+	return []Effect{
+		&Rainbow{Period: 5 * time.Second},
+		&TheaterChase{Step: 100 * time.Millisecond, Spacing: 3},
+		&Comet{Step: 60 * time.Millisecond, Length: 4},
+		&Twinkle{PerSecond: 8, Decay: 600 * time.Millisecond},
+		&Fire{Cooling: 55, Sparking: 120},
+		&Breathing{Period: 4 * time.Second},
+		&ColorWipe{Step: 80 * time.Millisecond},
+		&Larson{Step: 70 * time.Millisecond, Length: 3},
+	}
+}
+
+// Effects with a Color field draw with it, or with the palette's selected
+// color when it's left off (the zero value)
+func pick(c RGBW, f Frame) RGBW {
+	if c == off {
+		return f.Color
+	}
+	return c
+}
+
+// Func scale dims c to level/255
+func scale(c RGBW, level uint8) RGBW {
+	return Lerp(off, c, level)
+}
+
+// Type rng is a xorshift generator, enough for sparkles and flames without
+// any shared state
+type rng uint32
+
+func (r *rng) next() uint32 {
+	if *r == 0 {
+		*r = 2463534242
+	}
+	x := uint32(*r)
+	x ^= x << 13
+	x ^= x >> 17
+	x ^= x << 5
+	*r = rng(x)
+	return x
+}
+
+// Func intn returns a number in [0, n)
+func (r *rng) intn(n int) int {
+	if n <= 0 {
+		return 0
+	}
+	return int(r.next() % uint32(n))
+}
+
+// Type Rainbow spreads the color wheel across the strip and turns it once
+// per Period
+type Rainbow struct {
+	Period time.Duration
+}
+
+func (r *Rainbow) Name() string { return "Rainbow" }
+
+func (r *Rainbow) Params() []Param {
+	return []Param{{"Period", r.Period}}
+}
+
+func (r *Rainbow) Render(f Frame, pixels []RGBW) {
+	var offset uint32
+	if r.Period > 0 {
+		offset = uint32((f.Elapsed % r.Period) * 65536 / r.Period)
+	}
+	for i := range pixels {
+		hue := offset + uint32(i*65536/len(pixels))
+		pixels[i] = HSV{H: uint16(hue), S: 255, V: 255}.RGBW()
+	}
+}
+
+// Type TheaterChase lights every Spacing'th pixel and marches them along one
+// pixel per Step
+type TheaterChase struct {
+	Step    time.Duration
+	Spacing int
+	Color   RGBW
+}
+
+func (t *TheaterChase) Name() string { return "Theater Chase" }
+
+func (t *TheaterChase) Params() []Param {
+	return []Param{{"Step", t.Step}, {"Spacing", t.Spacing}, {"Color", t.Color}}
+}
+
+func (t *TheaterChase) Render(f Frame, pixels []RGBW) {
+	spacing := t.Spacing
+	if spacing < 2 {
+		spacing = 2
+	}
+	step := 0
+	if t.Step > 0 {
+		step = int(f.Elapsed / t.Step)
+	}
+	color := pick(t.Color, f)
+	for i := range pixels {
+		if (i+spacing-step%spacing)%spacing == 0 {
+			pixels[i] = color
+		} else {
+			pixels[i] = off
+		}
+	}
+}
+
+// Type Comet runs a head along the strip, one pixel per Step, trailing Length
+// pixels that fade out behind it
+type Comet struct {
+	Step   time.Duration
+	Length int
+	Color  RGBW
+}
+
+func (c *Comet) Name() string { return "Comet" }
+
+func (c *Comet) Params() []Param {
+	return []Param{{"Step", c.Step}, {"Length", c.Length}, {"Color", c.Color}}
+}
+
+func (c *Comet) Render(f Frame, pixels []RGBW) {
+	fill(pixels, off)
+	length := c.Length
+	if length < 1 {
+		length = 1
+	}
+	step := 0
+	if c.Step > 0 {
+		step = int(f.Elapsed / c.Step)
+	}
+	// The head runs off the end until the whole tail has left
+	head := step % (len(pixels) + length)
+	color := pick(c.Color, f)
+	for k := 0; k < length; k++ {
+		if i := head - k; i >= 0 && i < len(pixels) {
+			pixels[i] = scale(color, uint8(255*(length-k)/length))
+		}
+	}
+}
+
+// Type Twinkle lights random pixels, PerSecond of them a second, each fading
+// linearly to off over Decay. Random colors are used when RandomColors is set.
+type Twinkle struct {
+	PerSecond    int
+	Decay        time.Duration
+	Color        RGBW
+	RandomColors bool
+
+	rng     rng
+	pending time.Duration
+	sparks  []spark
+}
+
+// Type spark is a twinkling pixel's color and when it was lit
+type spark struct {
+	color RGBW
+	lit   time.Duration
+}
+
+func (t *Twinkle) Name() string { return "Twinkle" }
+
+func (t *Twinkle) Params() []Param {
+	return []Param{{"PerSecond", t.PerSecond}, {"Decay", t.Decay}, {"Color", t.Color}, {"RandomColors", t.RandomColors}}
+}
+
+func (t *Twinkle) Render(f Frame, pixels []RGBW) {
+	if f.Number == 0 || len(t.sparks) != len(pixels) {
+		t.pending = 0
+		t.sparks = make([]spark, len(pixels))
+	}
+	if len(pixels) == 0 {
+		return
+	}
+
+	t.pending += f.Delta * time.Duration(t.PerSecond)
+	for ; t.pending >= time.Second; t.pending -= time.Second {
+		color := pick(t.Color, f)
+		if t.RandomColors {
+			color = Wheel(byte(t.rng.next()))
+		}
+		t.sparks[t.rng.intn(len(pixels))] = spark{color, f.Elapsed}
+	}
+
+	// Each spark is at full brightness when lit and off once Decay has passed
+	for i, s := range t.sparks {
+		age := f.Elapsed - s.lit
+		switch {
+		case age <= 0:
+			pixels[i] = s.color
+		case age >= t.Decay:
+			pixels[i] = off
+		default:
+			pixels[i] = scale(s.color, uint8(255-255*age/t.Decay))
+		}
+	}
+}
+
+// Fire2012 was tuned running a step at 60 FPS
+const DefaultFireStep = time.Second / 60
+
+// The most Fire steps run for one frame, so a stall doesn't replay
+const maxFireSteps = 8
+
+// Type Fire is Mark Kriegsman's Fire2012 flame. Cooling is how fast the
+// flame cools, higher is shorter, and Sparking is the chance out of 255 of a
+// new spark each step, higher is more roaring. Step is how often the flame
+// moves, shorter is faster, DefaultFireStep when zero. The flame rises from
+// pixel 0 unless Reverse is set.
+type Fire struct {
+	Cooling  uint8
+	Sparking uint8
+	Step     time.Duration
+	Reverse  bool
+
+	heat    []uint8
+	rng     rng
+	pending time.Duration
+}
+
+func (fi *Fire) Name() string { return "Fire" }
+
+func (fi *Fire) Params() []Param {
+	return []Param{{"Cooling", fi.Cooling}, {"Sparking", fi.Sparking}, {"Step", fi.Step}, {"Reverse", fi.Reverse}}
+}
+
+func (fi *Fire) Render(f Frame, pixels []RGBW) {
+	n := len(pixels)
+	if f.Number == 0 || len(fi.heat) != n {
+		fi.heat = make([]uint8, n)
+		fi.pending = 0
+	}
+	if n == 0 {
+		return
+	}
+
+	step := fi.Step
+	if step <= 0 {
+		step = DefaultFireStep
+	}
+	fi.pending += f.Delta
+	for i := 0; fi.pending >= step; i++ {
+		if i == maxFireSteps {
+			fi.pending = 0
+			break
+		}
+		fi.step()
+		fi.pending -= step
+	}
+
+	for i, h := range fi.heat {
+		if fi.Reverse {
+			i = n - 1 - i
+		}
+		pixels[i] = HeatColor(h)
+	}
+}
+
+// Func step runs one step of the simulation
+func (fi *Fire) step() {
+	heat := fi.heat
+	n := len(heat)
+
+	// Every cell cools down a little
+	for i := range heat {
+		cool := fi.rng.intn(int(fi.Cooling)*10/n + 2)
+		if cool > int(heat[i]) {
+			heat[i] = 0
+		} else {
+			heat[i] -= uint8(cool)
+		}
+	}
+
+	// Heat drifts up and diffuses
+	for k := n - 1; k >= 2; k-- {
+		heat[k] = uint8((int(heat[k-1]) + 2*int(heat[k-2])) / 3)
+	}
+
+	// New sparks near the bottom
+	if fi.rng.intn(256) < int(fi.Sparking) {
+		y := fi.rng.intn(7)
+		if y >= n {
+			y = n - 1
+		}
+		heat[y] = addSat(heat[y], uint8(160+fi.rng.intn(96)))
+	}
+}
+
+// Func HeatColor maps a temperature onto black, red, yellow and white
+func HeatColor(temperature uint8) RGBW {
+	// Scale to 0..191 then split into three 64 step bands
+	t := uint8(uint16(temperature) * 191 / 255)
+	ramp := (t & 0x3F) << 2
+	switch {
+	case t&0x80 != 0:
+		return RGBW{R: 255, G: 255, B: ramp}
+	case t&0x40 != 0:
+		return RGBW{R: 255, G: ramp}
+	default:
+		return RGBW{R: ramp}
+	}
+}
+
+// Type Breathing eases the color in and out on a sine once per Period
+type Breathing struct {
+	Period time.Duration
+	Color  RGBW
+}
+
+func (b *Breathing) Name() string { return "Breathing" }
+
+func (b *Breathing) Params() []Param {
+	return []Param{{"Period", b.Period}, {"Color", b.Color}}
+}
+
+func (b *Breathing) Render(f Frame, pixels []RGBW) {
+	color := pick(b.Color, f)
+	if b.Period <= 0 {
+		fill(pixels, color)
+		return
+	}
+	phase := float64(f.Elapsed%b.Period) / float64(b.Period)
+	level := (1 - math.Cos(2*math.Pi*phase)) / 2
+	fill(pixels, scale(color, uint8(level*255+0.5)))
+}
+
+// Type ColorWipe fills the strip with the color one pixel per Step, then
+// wipes it off again the same way
+type ColorWipe struct {
+	Step  time.Duration
+	Color RGBW
+}
+
+func (c *ColorWipe) Name() string { return "Color Wipe" }
+
+func (c *ColorWipe) Params() []Param {
+	return []Param{{"Step", c.Step}, {"Color", c.Color}}
+}
+
+func (c *ColorWipe) Render(f Frame, pixels []RGBW) {
+	n := len(pixels)
+	if n == 0 {
+		return
+	}
+	step := 0
+	if c.Step > 0 {
+		step = int(f.Elapsed / c.Step)
+	}
+	front, behind := pick(c.Color, f), off
+	if (step/n)%2 == 1 {
+		front, behind = behind, front
+	}
+	for i := range pixels {
+		if i <= step%n {
+			pixels[i] = front
+		} else {
+			pixels[i] = behind
+		}
+	}
+}
+
+// Type Larson is a Cylon/Larson scanner, a head bouncing end to end one
+// pixel per Step with a trail of Length fading pixels
+type Larson struct {
+	Step   time.Duration
+	Length int
+	Color  RGBW
+}
+
+func (l *Larson) Name() string { return "Larson Scanner" }
+
+func (l *Larson) Params() []Param {
+	return []Param{{"Step", l.Step}, {"Length", l.Length}, {"Color", l.Color}}
+}
+
+func (l *Larson) Render(f Frame, pixels []RGBW) {
+	fill(pixels, off)
+	if len(pixels) == 0 {
+		return
+	}
+	step := 0
+	if l.Step > 0 {
+		step = int(f.Elapsed / l.Step)
+	}
+	color := pick(l.Color, f)
+	// Draw the oldest part of the trail first so the head wins
+	for k := l.Length; k >= 0; k-- {
+		if step-k < 0 {
+			continue
+		}
+		i := bounce(step-k, len(pixels))
+		pixels[i] = scale(color, uint8(255*(l.Length+1-k)/(l.Length+1)))
+	}
+}
@@ -0,0 +1,104 @@
+package neopixel_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/davidadeleon/gophercon2022Badge/neopixel"
+)
+
+// Type clip renders an effect frame after frame into the same pixels, the
+// way EffectsController does
+type clip struct {
+	effect  neopixel.Effect
+	pixels  []neopixel.RGBW
+	number  int
+	elapsed time.Duration
+}
+
+func (c *clip) next(delta time.Duration) []neopixel.RGBW {
+	c.elapsed += delta
+	c.effect.Render(neopixel.Frame{Number: c.number, Elapsed: c.elapsed, Delta: delta, Color: color}, c.pixels)
+	c.number++
+	return c.pixels
+}
+
+func TestTwinkleFadesLinearly(t *testing.T) {
+	c := &clip{effect: &neopixel.Twinkle{PerSecond: 1, Decay: 100 * time.Millisecond}, pixels: make([]neopixel.RGBW, 1)}
+	if p := c.next(0)[0]; p != off {
+		t.Fatalf("frame 0 = %v, want off", p)
+	}
+	// A second in, one pixel lights
+	if p := c.next(time.Second)[0]; p != color {
+		t.Fatalf("lit pixel = %v, want %v", p, color)
+	}
+	for _, tc := range []struct {
+		delta time.Duration
+		level uint8
+	}{
+		{25 * time.Millisecond, 192},
+		{25 * time.Millisecond, 128},
+		{25 * time.Millisecond, 64},
+		{20 * time.Millisecond, 13},
+		{5 * time.Millisecond, 0},
+	} {
+		want := neopixel.Lerp(off, color, tc.level)
+		if p := c.next(tc.delta)[0]; p != want {
+			t.Errorf("at %v = %v, want %v", c.elapsed, p, want)
+		}
+	}
+}
+
+func TestTwinkleRate(t *testing.T) {
+	c := &clip{effect: &neopixel.Twinkle{PerSecond: 10, Decay: time.Hour}, pixels: make([]neopixel.RGBW, 1000)}
+	c.next(0)
+	for i := 0; i < 50; i++ {
+		c.next(10 * time.Millisecond)
+	}
+	// Five sparks in half a second, unless two landed on the same pixel
+	if on := lit(t, c.pixels, color); len(on) < 4 || len(on) > 5 {
+		t.Errorf("%d pixels lit after half a second at 10 a second", len(on))
+	}
+}
+
+func TestFireSpeed(t *testing.T) {
+	run := func(step, delta time.Duration, frames int) []neopixel.RGBW {
+		c := &clip{effect: &neopixel.Fire{Cooling: 55, Sparking: 200, Step: step}, pixels: make([]neopixel.RGBW, 16)}
+		for i := 0; i < frames; i++ {
+			c.next(delta)
+		}
+		return c.pixels
+	}
+	equal := func(a, b []neopixel.RGBW) bool {
+		for i := range a {
+			if a[i] != b[i] {
+				return false
+			}
+		}
+		return true
+	}
+
+	// The flame depends on the time that's passed, not the frame rate
+	a := run(10*time.Millisecond, 10*time.Millisecond, 40)
+	b := run(10*time.Millisecond, 20*time.Millisecond, 20)
+	if !equal(a, b) {
+		t.Error("flame differs at half the frame rate")
+	}
+	if equal(a, make([]neopixel.RGBW, 16)) {
+		t.Error("no flame after 40 steps")
+	}
+	// Half the Step runs twice as fast
+	if c := run(5*time.Millisecond, 10*time.Millisecond, 20); !equal(a, c) {
+		t.Error("flame differs at half the step")
+	}
+
+	// Frames shorter than a step hold the flame until one has passed
+	c := &clip{effect: &neopixel.Fire{Cooling: 55, Sparking: 200, Step: 10 * time.Millisecond}, pixels: make([]neopixel.RGBW, 16)}
+	for i := 0; i < 20; i++ {
+		c.next(10 * time.Millisecond)
+	}
+	before := append([]neopixel.RGBW(nil), c.pixels...)
+	if !equal(before, c.next(4*time.Millisecond)) || !equal(before, c.next(4*time.Millisecond)) {
+		t.Error("flame moved before a step had passed")
+	}
+}
@@ -0,0 +1,67 @@
+package neopixel_test
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+
+	"github.com/davidadeleon/gophercon2022Badge/neopixel"
+)
+
+// Type strip is a Writer that keeps every frame sent
+type strip struct {
+	mu     sync.Mutex
+	frames [][]byte
+}
+
+func (s *strip) Write(buf []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.frames = append(s.frames, append([]byte(nil), buf...))
+	return len(buf), nil
+}
+
+func (s *strip) last() []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.frames) == 0 {
+		return nil
+	}
+	return s.frames[len(s.frames)-1]
+}
+
+// Func sent returns the frames sent so far
+func (s *strip) sent() [][]byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([][]byte(nil), s.frames...)
+}
+
+// Func newStrip returns a controller for pixels pixels with gamma off and
+// full brightness, so what's written is what's sent
+func newStrip(order neopixel.ColorOrder, pixels int) (*strip, *neopixel.NeoPixelController) {
+	s := &strip{}
+	n := &neopixel.NeoPixelController{Controller: s, Order: order, Brightness: 0xFF}
+	n.Init(pixels)
+	n.SetGamma(nil)
+	return s, n
+}
+
+func TestControllerWrite(t *testing.T) {
+	s, n := newStrip(neopixel.OrderGRBW, 3)
+	n.Write([]neopixel.RGBW{{R: 1, G: 2, B: 3, W: 4}, {R: 5}})
+	want := []byte{2, 1, 3, 4, 0, 5, 0, 0, 0, 0, 0, 0}
+	if !bytes.Equal(s.last(), want) {
+		t.Errorf("sent % x, want % x", s.last(), want)
+	}
+	if c := n.NeoPixels[0].Color; c != (neopixel.RGBW{R: 1, G: 2, B: 3, W: 4}) {
+		t.Errorf("pixel 0 stored as %v", c)
+	}
+
+	// Brightness scales what's sent, not what's stored
+	n.SetBrightness(0x80)
+	n.Fill(neopixel.RGBW{R: 0xFF})
+	if got := s.last()[1]; got != 0x80 {
+		t.Errorf("red at half brightness sent as %02x", got)
+	}
+}
@@ -0,0 +1,44 @@
+package neopixel
+
+// Type ColorOrder is the wire format of a strip: how many channels each pixel
+// has and the order they're clocked out in. The zero value is GRBW, the
+// badge's RGBW stick.
+type ColorOrder uint8
+
+const (
+	OrderGRBW ColorOrder = iota // SK6812 RGBW
+	OrderRGBW
+	OrderGRB // WS2812B
+	OrderRGB // WS2811 and some APA106
+	OrderBGR
+)
+
+// Func Channels returns the number of bytes per pixel
+func (o ColorOrder) Channels() int {
+	switch o {
+	case OrderGRBW, OrderRGBW:
+		return 4
+	default:
+		return 3
+	}
+}
+
+// Func Encode appends c to dst in wire order. On 3 channel strips the white
+// channel is mixed into red, green and blue.
+func (o ColorOrder) Encode(dst []byte, c RGBW) []byte {
+	if o.Channels() == 3 && c.W != 0 {
+		c = RGBW{R: addSat(c.R, c.W), G: addSat(c.G, c.W), B: addSat(c.B, c.W)}
+	}
+	switch o {
+	case OrderRGBW:
+		return append(dst, c.R, c.G, c.B, c.W)
+	case OrderGRB:
+		return append(dst, c.G, c.R, c.B)
+	case OrderRGB:
+		return append(dst, c.R, c.G, c.B)
+	case OrderBGR:
+		return append(dst, c.B, c.G, c.R)
+	default:
+		return append(dst, c.G, c.R, c.B, c.W)
+	}
+}
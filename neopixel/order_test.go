@@ -0,0 +1,63 @@
+package neopixel_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/davidadeleon/gophercon2022Badge/neopixel"
+)
+
+func TestEncode(t *testing.T) {
+	c := neopixel.RGBW{R: 1, G: 2, B: 3, W: 4}
+	// W folds into R, G and B on 3 channel strips, saturating
+	bright := neopixel.RGBW{R: 10, G: 20, B: 250, W: 10}
+	for _, tc := range []struct {
+		name  string
+		order neopixel.ColorOrder
+		in    neopixel.RGBW
+		want  []byte
+	}{
+		{"GRBW", neopixel.OrderGRBW, c, []byte{2, 1, 3, 4}},
+		{"RGBW", neopixel.OrderRGBW, c, []byte{1, 2, 3, 4}},
+		{"GRB", neopixel.OrderGRB, c, []byte{6, 5, 7}},
+		{"RGB", neopixel.OrderRGB, c, []byte{5, 6, 7}},
+		{"BGR", neopixel.OrderBGR, c, []byte{7, 6, 5}},
+		{"GRBW no fold", neopixel.OrderGRBW, bright, []byte{20, 10, 250, 10}},
+		{"GRB saturated", neopixel.OrderGRB, bright, []byte{30, 20, 255}},
+		{"RGB saturated", neopixel.OrderRGB, bright, []byte{20, 30, 255}},
+		{"BGR saturated", neopixel.OrderBGR, bright, []byte{255, 30, 20}},
+		{"RGB white only", neopixel.OrderRGB, neopixel.RGBW{W: 200}, []byte{200, 200, 200}},
+	} {
+		got := tc.order.Encode([]byte{0xAA}, tc.in)
+		if want := append([]byte{0xAA}, tc.want...); !bytes.Equal(got, want) {
+			t.Errorf("%s: Encode(%+v) = % x, want % x", tc.name, tc.in, got, want)
+		}
+		if len(tc.want) != tc.order.Channels() {
+			t.Errorf("%s: %d channels, want %d", tc.name, tc.order.Channels(), len(tc.want))
+		}
+	}
+}
+
+func TestClearSize(t *testing.T) {
+	for _, tc := range []struct {
+		order    neopixel.ColorOrder
+		channels int
+	}{
+		{neopixel.OrderGRBW, 4},
+		{neopixel.OrderRGBW, 4},
+		{neopixel.OrderGRB, 3},
+		{neopixel.OrderRGB, 3},
+		{neopixel.OrderBGR, 3},
+	} {
+		s, n := newStrip(tc.order, 8)
+		n.Fill(neopixel.RGBW{R: 0xFF, W: 0xFF})
+		n.Clear()
+		if got := s.last(); len(got) != 8*tc.channels || !bytes.Equal(got, make([]byte, len(got))) {
+			t.Errorf("order %d: Clear sent % x, want %d zeros", tc.order, got, 8*tc.channels)
+		}
+		n.Show()
+		if got := len(s.last()); got != 8*tc.channels {
+			t.Errorf("order %d: Show sent %d bytes, want %d", tc.order, got, 8*tc.channels)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package neopixel
+
+import "sync"
+
+// Type Palette is a list of colors with a selected one. It's safe to use from
+// several goroutines, buttons change the selection while effects render.
+type Palette struct {
+	mu     sync.Mutex
+	colors []RGBW
+	index  int
+}
+
+// Func NewPalette returns a palette of colors with the first one selected
+func NewPalette(colors ...RGBW) *Palette {
+	p := &Palette{}
+	p.Set(colors...)
+	return p
+}
+
+// Func DefaultPalette returns the badge's colors at full range,
+// NeoPixelController dims them on output
+func DefaultPalette() *Palette {
+	return NewPalette(
+		RGBW{0xFF, 0x00, 0x00, 0x00}, // Red
+		RGBW{0x00, 0xFF, 0x00, 0x00}, // Green
+		RGBW{0x00, 0x00, 0xFF, 0x00}, // Blue
+		RGBW{0x00, 0x00, 0x00, 0xFF}, // White
+		RGBW{0x00, 0xFF, 0xFF, 0x00}, // Gopher
+		RGBW{0xFF, 0xFF, 0x00, 0x00}, // Yellow
+		RGBW{0xFF, 0x80, 0x00, 0x00}, // Orange
+	)
+}
+
+// Func Set replaces the colors and selects the first one
+func (p *Palette) Set(colors ...RGBW) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.colors = append([]RGBW(nil), colors...)
+	p.index = 0
+}
+
+// Func Len returns the number of colors
+func (p *Palette) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.colors)
+}
+
+// Func Index returns the index of the selected color
+func (p *Palette) Index() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.index
+}
+
+// Func Current returns the selected color, off if the palette is empty
+func (p *Palette) Current() RGBW {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.at(p.index)
+}
+
+// Func At returns color i, wrapping round in both directions
+func (p *Palette) At(i int) RGBW {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.at(i)
+}
+
+func (p *Palette) at(i int) RGBW {
+	if len(p.colors) == 0 {
+		return off
+	}
+	return p.colors[wrap(i, len(p.colors))]
+}
+
+// Func Next selects and returns the next color, wrapping after the last
+func (p *Palette) Next() RGBW {
+	return p.step(1)
+}
+
+// Func Previous selects and returns the previous color, wrapping before the
+// first
+func (p *Palette) Previous() RGBW {
+	return p.step(-1)
+}
+
+func (p *Palette) step(delta int) RGBW {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.colors) == 0 {
+		return off
+	}
+	p.index = wrap(p.index+delta, len(p.colors))
+	return p.colors[p.index]
+}
+
+// Func Select selects color i, it returns false if i is out of range
+func (p *Palette) Select(i int) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if i < 0 || i >= len(p.colors) {
+		return false
+	}
+	p.index = i
+	return true
+}
+
+// Func Colors returns a copy of the colors
+func (p *Palette) Colors() []RGBW {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]RGBW(nil), p.colors...)
+}
+
+func wrap(i, n int) int {
+	i %= n
+	if i < 0 {
+		i += n
+	}
+	return i
+}
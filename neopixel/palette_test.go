@@ -0,0 +1,68 @@
+package neopixel_test
+
+import (
+	"testing"
+
+	"github.com/davidadeleon/gophercon2022Badge/neopixel"
+)
+
+func TestPalette(t *testing.T) {
+	red, green, blue := neopixel.RGBW{R: 0xFF}, neopixel.RGBW{G: 0xFF}, neopixel.RGBW{B: 0xFF}
+	// The steps run in order on one palette
+	p := neopixel.NewPalette(red, green, blue)
+	for _, tc := range []struct {
+		name  string
+		step  func(p *neopixel.Palette) neopixel.RGBW
+		index int
+		want  neopixel.RGBW
+	}{
+		{"next", (*neopixel.Palette).Next, 1, green},
+		{"next to last", (*neopixel.Palette).Next, 2, blue},
+		{"next wraps", (*neopixel.Palette).Next, 0, red},
+		{"previous wraps", (*neopixel.Palette).Previous, 2, blue},
+		{"previous", (*neopixel.Palette).Previous, 1, green},
+		{"select", func(p *neopixel.Palette) neopixel.RGBW {
+			if !p.Select(2) {
+				t.Error("Select(2) refused")
+			}
+			return p.Current()
+		}, 2, blue},
+		{"select past the end", func(p *neopixel.Palette) neopixel.RGBW {
+			if p.Select(3) {
+				t.Error("Select(3) accepted")
+			}
+			return p.Current()
+		}, 2, blue},
+		{"select negative", func(p *neopixel.Palette) neopixel.RGBW {
+			if p.Select(-1) {
+				t.Error("Select(-1) accepted")
+			}
+			return p.Current()
+		}, 2, blue},
+	} {
+		if got := tc.step(p); got != tc.want || p.Index() != tc.index {
+			t.Errorf("%s: %v at %d, want %v at %d", tc.name, got, p.Index(), tc.want, tc.index)
+		}
+	}
+}
+
+func TestPaletteSetCopies(t *testing.T) {
+	colors := []neopixel.RGBW{{R: 1}, {R: 2}}
+	p := neopixel.NewPalette()
+	if p.Current() != off || p.Next() != off {
+		t.Error("empty palette isn't off")
+	}
+	p.Set(colors...)
+	colors[0] = neopixel.RGBW{B: 9}
+	if got := p.Current(); got != (neopixel.RGBW{R: 1}) {
+		t.Errorf("Current() = %v after changing Set's input", got)
+	}
+	got := p.Colors()
+	got[1] = neopixel.RGBW{B: 9}
+	if c := p.At(1); c != (neopixel.RGBW{R: 2}) {
+		t.Errorf("At(1) = %v after changing Colors' result", c)
+	}
+	if c := p.At(-1); c != (neopixel.RGBW{R: 2}) {
+		t.Errorf("At(-1) = %v, want the last color", c)
+	}
+}
@@ -0,0 +1,76 @@
+package neopixel
+
+// Default current model, roughly a WS2812/SK6812 LED: each channel draws up
+// to 20mA at full on and every pixel draws about 1mA with all channels off
+const (
+	DefaultMilliampsPerChannel = 20
+	DefaultIdleMilliamps       = 1
+)
+
+// Type PowerBudget models what the strip draws and caps it. The estimate is
+// linear in the channel values actually sent, after gamma and brightness.
+type PowerBudget struct {
+	// MilliampsPerChannel is one channel's draw at 255
+	MilliampsPerChannel int
+	// IdleMilliamps is one pixel's draw with everything off
+	IdleMilliamps int
+	// Milliamps is the most the strip may draw, frames estimated above it are
+	// scaled down evenly to fit. Zero is no limit.
+	Milliamps int
+}
+
+// Type PowerDraw is the estimated current of the last frame shown
+type PowerDraw struct {
+	// Requested is what the frame would have drawn without the budget
+	Requested int
+	// Milliamps is what it draws as sent
+	Milliamps int
+	// Limited is set when the frame was scaled down
+	Limited bool
+}
+
+// Func SetPowerBudget sets the most the strip may draw in milliamps, zero
+// removes the limit. It applies from the next frame.
+func (n *NeoPixelController) SetPowerBudget(milliamps int) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.Power.Milliamps = milliamps
+}
+
+// Func PowerDraw returns the estimated draw of the last frame shown
+func (n *NeoPixelController) PowerDraw() PowerDraw {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.draw
+}
+
+// Func limit estimates the draw of the encoded frame in n.buf and scales it
+// down to fit the budget, n.mu must be held
+func (n *NeoPixelController) limit() {
+	idle := n.Power.IdleMilliamps * len(n.NeoPixels)
+	sum := 0
+	for _, b := range n.buf {
+		sum += int(b)
+	}
+	milliamps := func(sum int) int {
+		return idle + (sum*n.Power.MilliampsPerChannel+254)/255
+	}
+
+	n.draw = PowerDraw{Requested: milliamps(sum)}
+	if n.Power.Milliamps > 0 && n.draw.Requested > n.Power.Milliamps && sum > 0 {
+		// Largest channel total the budget leaves room for, rounded down so
+		// the scaled frame stays under it
+		allowed := 0
+		if n.Power.Milliamps > idle && n.Power.MilliampsPerChannel > 0 {
+			allowed = (n.Power.Milliamps - idle) * 255 / n.Power.MilliampsPerChannel
+		}
+		scaled := 0
+		for i, b := range n.buf {
+			n.buf[i] = byte(int(b) * allowed / sum)
+			scaled += int(n.buf[i])
+		}
+		sum = scaled
+		n.draw.Limited = true
+	}
+	n.draw.Milliamps = milliamps(sum)
+}
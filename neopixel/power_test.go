@@ -0,0 +1,48 @@
+package neopixel_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/davidadeleon/gophercon2022Badge/neopixel"
+)
+
+var white = neopixel.RGBW{R: 0xFF, G: 0xFF, B: 0xFF, W: 0xFF}
+
+func TestPowerBudget(t *testing.T) {
+	s, n := newStrip(neopixel.OrderGRBW, 8)
+	// 8 pixels idle plus 4 channels at full on each
+	requested := 8*neopixel.DefaultIdleMilliamps + 8*4*neopixel.DefaultMilliampsPerChannel
+	full := bytes.Repeat([]byte{0xFF}, 8*4)
+
+	n.SetPowerBudget(200)
+	n.Fill(white)
+	draw := n.PowerDraw()
+	if !draw.Limited || draw.Milliamps > 200 || draw.Requested != requested {
+		t.Errorf("full white at 200mA drew %+v, want limited to 200 of %d", draw, requested)
+	}
+	if bytes.Equal(s.last(), full) {
+		t.Error("full white sent unscaled")
+	}
+
+	// Nothing is left for the channels once the idle draw is paid
+	for _, budget := range []int{8 * neopixel.DefaultIdleMilliamps, 1} {
+		n.SetPowerBudget(budget)
+		n.Fill(white)
+		if !bytes.Equal(s.last(), make([]byte, len(full))) {
+			t.Errorf("budget %dmA sent % x, want blank", budget, s.last())
+		}
+		if draw := n.PowerDraw(); !draw.Limited || draw.Requested != requested {
+			t.Errorf("budget %dmA drew %+v", budget, draw)
+		}
+	}
+
+	n.SetPowerBudget(0)
+	n.Fill(white)
+	if !bytes.Equal(s.last(), full) {
+		t.Errorf("no budget sent % x, want full white", s.last())
+	}
+	if draw := n.PowerDraw(); draw.Limited || draw.Milliamps != requested || draw.Requested != requested {
+		t.Errorf("no budget drew %+v, want %d unlimited", draw, requested)
+	}
+}
@@ -0,0 +1,131 @@
+package neopixel
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Effects render at DefaultFPS unless EffectsController.FPS says otherwise
+const DefaultFPS = 50
+
+// Type Tick is one frame slot handed out by a Scheduler
+type Tick struct {
+	// Number is the slot's index since Run started, it jumps when frames are
+	// skipped
+	Number int
+	// Elapsed is the time since Run started and Delta the time since the
+	// previous frame
+	Elapsed time.Duration
+	Delta   time.Duration
+}
+
+// Type SchedulerStats are measured frame timings
+type SchedulerStats struct {
+	Frames  int
+	Skipped int
+	// Busy is how long the frame callback took: the last one, the worst one
+	// and a running average
+	LastBusy time.Duration
+	MaxBusy  time.Duration
+	AvgBusy  time.Duration
+	// AvgInterval is the running average time between frames
+	AvgInterval time.Duration
+}
+
+// Func FPS returns the measured frame rate
+func (s SchedulerStats) FPS() float32 {
+	if s.AvgInterval <= 0 {
+		return 0
+	}
+	return float32(time.Second) / float32(s.AvgInterval)
+}
+
+// Type Scheduler calls a function at a fixed frame rate. Frames are timed
+// from when Run started rather than from each other so the rate doesn't
+// drift, and when a frame overruns the slots it missed are skipped instead of
+// being rendered late.
+type Scheduler struct {
+	FPS int
+
+	mu    sync.Mutex
+	stats SchedulerStats
+}
+
+// Func NewScheduler returns a Scheduler ticking fps times a second
+func NewScheduler(fps int) *Scheduler {
+	return &Scheduler{FPS: fps}
+}
+
+// Func Stats returns the timings measured so far
+func (s *Scheduler) Stats() SchedulerStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stats
+}
+
+// Func Period returns the time between frames
+func (s *Scheduler) Period() time.Duration {
+	fps := s.FPS
+	if fps <= 0 {
+		fps = DefaultFPS
+	}
+	return time.Second / time.Duration(fps)
+}
+
+// Func Run calls frame for every tick until ctx is cancelled
+func (s *Scheduler) Run(ctx context.Context, frame func(Tick)) {
+	period := s.Period()
+	start := time.Now()
+	last := start
+	next := 0
+
+	s.mu.Lock()
+	s.stats = SchedulerStats{}
+	s.mu.Unlock()
+
+	for {
+		if wait := time.Duration(next)*period - time.Since(start); wait > 0 {
+			time.Sleep(wait)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		now := time.Now()
+		frame(Tick{Number: next, Elapsed: now.Sub(start), Delta: now.Sub(last)})
+		busy := time.Since(now)
+
+		// Carry on from the latest slot that has already started
+		skipped := 0
+		next++
+		if current := int(time.Since(start) / period); current > next {
+			skipped = current - next
+			next = current
+		}
+
+		s.mu.Lock()
+		st := &s.stats
+		st.Frames++
+		st.Skipped += skipped
+		st.LastBusy = busy
+		if busy > st.MaxBusy {
+			st.MaxBusy = busy
+		}
+		if st.Frames == 1 {
+			st.AvgBusy = busy
+		} else {
+			st.AvgBusy += (busy - st.AvgBusy) / 8
+			interval := now.Sub(last)
+			if st.AvgInterval == 0 {
+				st.AvgInterval = interval
+			} else {
+				st.AvgInterval += (interval - st.AvgInterval) / 8
+			}
+		}
+		s.mu.Unlock()
+		last = now
+	}
+}
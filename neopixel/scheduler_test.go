@@ -0,0 +1,41 @@
+package neopixel_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/davidadeleon/gophercon2022Badge/neopixel"
+)
+
+func TestSchedulerSkipsOverruns(t *testing.T) {
+	s := neopixel.NewScheduler(100)
+	period := s.Period()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var ticks []neopixel.Tick
+	s.Run(ctx, func(tick neopixel.Tick) {
+		ticks = append(ticks, tick)
+		if len(ticks) == 1 {
+			// Overrun the first frame by a few slots
+			time.Sleep(3*period + period/2)
+		}
+		if len(ticks) == 3 {
+			cancel()
+		}
+	})
+
+	if len(ticks) != 3 {
+		t.Fatalf("%d frames after cancel", len(ticks))
+	}
+	if st := s.Stats(); st.Skipped == 0 || st.MaxBusy < 3*period {
+		t.Errorf("stats %+v after overrunning", st)
+	}
+	if ticks[0].Number != 0 || ticks[1].Number < 3 {
+		t.Errorf("ticks %d then %d, want the missed slots skipped", ticks[0].Number, ticks[1].Number)
+	}
+	if ticks[2].Number <= ticks[1].Number {
+		t.Errorf("tick %d after %d", ticks[2].Number, ticks[1].Number)
+	}
+}
@@ -0,0 +1,107 @@
+package neopixel
+
+// Type Span is a run of pixels on one strip. Reverse runs it from the far
+// end, for strips mounted the other way round.
+type Span struct {
+	Strip   *NeoPixelController
+	Start   int
+	Len     int
+	Reverse bool
+}
+
+// Type Segment is a named logical strip made of one or more spans, such as
+// half of a stick or two sticks end to end. Logical pixel 0 is the first
+// pixel of the first span.
+type Segment struct {
+	Name  string
+	Spans []Span
+}
+
+// Func NewSegment returns a segment joining spans in order
+func NewSegment(name string, spans ...Span) *Segment {
+	return &Segment{Name: name, Spans: spans}
+}
+
+// Func Whole returns a segment covering all of a strip
+func Whole(name string, strip *NeoPixelController) *Segment {
+	return NewSegment(name, Span{Strip: strip, Len: len(strip.NeoPixels)})
+}
+
+// Func Split cuts a strip into consecutive segments of the given lengths
+func Split(strip *NeoPixelController, names []string, lengths []int) []*Segment {
+	var segments []*Segment
+	start := 0
+	for i, name := range names {
+		if i >= len(lengths) {
+			break
+		}
+		segments = append(segments, NewSegment(name, Span{Strip: strip, Start: start, Len: lengths[i]}))
+		start += lengths[i]
+	}
+	return segments
+}
+
+// Func Join returns a segment running through segments one after the other
+func Join(name string, segments ...*Segment) *Segment {
+	joined := &Segment{Name: name}
+	for _, s := range segments {
+		joined.Spans = append(joined.Spans, s.Spans...)
+	}
+	return joined
+}
+
+// Func Len returns the number of logical pixels
+func (s *Segment) Len() int {
+	n := 0
+	for _, span := range s.Spans {
+		n += span.Len
+	}
+	return n
+}
+
+// Func Write shows pixels, in logical order, on the strips. Every strip the
+// segment touches is sent one frame.
+func (s *Segment) Write(pixels []RGBW) {
+	for _, span := range s.Spans {
+		n := span.Len
+		if n > len(pixels) {
+			n = len(pixels)
+		}
+		start := span.Start
+		if span.Reverse {
+			// Logical pixel 0 is the far end of a reversed span
+			start += span.Len - n
+		}
+		span.Strip.mu.Lock()
+		span.Strip.stage(start, pixels[:n], span.Reverse)
+		span.Strip.mu.Unlock()
+		pixels = pixels[n:]
+	}
+	s.flush()
+}
+
+// Func Clear switches the segment's pixels off
+func (s *Segment) Clear() {
+	for _, span := range s.Spans {
+		span.Strip.mu.Lock()
+		span.Strip.stage(span.Start, make([]RGBW, span.Len), false)
+		span.Strip.mu.Unlock()
+	}
+	s.flush()
+}
+
+// Func flush shows each strip the segment runs over once
+func (s *Segment) flush() {
+	for i, span := range s.Spans {
+		shown := false
+		for _, prev := range s.Spans[:i] {
+			if prev.Strip == span.Strip {
+				shown = true
+				break
+			}
+		}
+		if !shown {
+			span.Strip.flush()
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package neopixel_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/davidadeleon/gophercon2022Badge/neopixel"
+)
+
+// Func reds returns the red byte of every pixel in an RGB frame
+func reds(frame []byte) []byte {
+	var r []byte
+	for i := 0; i+2 < len(frame); i += 3 {
+		r = append(r, frame[i])
+	}
+	return r
+}
+
+// Func ramp returns n pixels with red counting up from 1
+func ramp(n int) []neopixel.RGBW {
+	pixels := make([]neopixel.RGBW, n)
+	for i := range pixels {
+		pixels[i].R = uint8(i + 1)
+	}
+	return pixels
+}
+
+func TestSegmentMapping(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		spans  func(a, b *neopixel.NeoPixelController) *neopixel.Segment
+		pixels int
+		a, b   []byte // red per pixel, b nil for no frames
+	}{
+		{"whole", func(a, b *neopixel.NeoPixelController) *neopixel.Segment {
+			return neopixel.Whole("a", a)
+		}, 4, []byte{1, 2, 3, 4}, nil},
+		{"split", func(a, b *neopixel.NeoPixelController) *neopixel.Segment {
+			return neopixel.Split(a, []string{"left", "right"}, []int{1, 3})[1]
+		}, 3, []byte{0, 1, 2, 3}, nil},
+		{"reversed", func(a, b *neopixel.NeoPixelController) *neopixel.Segment {
+			return neopixel.NewSegment("a", neopixel.Span{Strip: a, Start: 1, Len: 3, Reverse: true})
+		}, 3, []byte{0, 3, 2, 1}, nil},
+		{"reversed partial", func(a, b *neopixel.NeoPixelController) *neopixel.Segment {
+			return neopixel.NewSegment("a", neopixel.Span{Strip: a, Start: 1, Len: 3, Reverse: true})
+		}, 2, []byte{0, 0, 2, 1}, nil},
+		{"join", func(a, b *neopixel.NeoPixelController) *neopixel.Segment {
+			return neopixel.Join("ab",
+				neopixel.Whole("a", a),
+				neopixel.NewSegment("b", neopixel.Span{Strip: b, Len: 4, Reverse: true}))
+		}, 8, []byte{1, 2, 3, 4}, []byte{8, 7, 6, 5}},
+		{"join partial", func(a, b *neopixel.NeoPixelController) *neopixel.Segment {
+			return neopixel.Join("ab",
+				neopixel.Whole("a", a),
+				neopixel.NewSegment("b", neopixel.Span{Strip: b, Len: 4, Reverse: true}))
+		}, 6, []byte{1, 2, 3, 4}, []byte{0, 0, 6, 5}},
+	} {
+		sa, a := newStrip(neopixel.OrderRGB, 4)
+		sb, b := newStrip(neopixel.OrderRGB, 4)
+		s := tc.spans(a, b)
+		s.Write(ramp(tc.pixels))
+		if got := reds(sa.last()); !bytes.Equal(got, tc.a) {
+			t.Errorf("%s: strip a sent %v, want %v", tc.name, got, tc.a)
+		}
+		if tc.b == nil {
+			if len(sb.frames) != 0 {
+				t.Errorf("%s: strip b sent %d frames", tc.name, len(sb.frames))
+			}
+		} else if got := reds(sb.last()); !bytes.Equal(got, tc.b) {
+			t.Errorf("%s: strip b sent %v, want %v", tc.name, got, tc.b)
+		}
+	}
+}
+
+func TestSegmentOneFramePerStrip(t *testing.T) {
+	sa, a := newStrip(neopixel.OrderRGB, 4)
+	sb, b := newStrip(neopixel.OrderRGB, 4)
+	halves := neopixel.Split(a, []string{"left", "right"}, []int{2, 2})
+	s := neopixel.Join("ring", halves[1], neopixel.Whole("b", b), halves[0])
+
+	s.Write(ramp(s.Len()))
+	if len(sa.frames) != 1 || len(sb.frames) != 1 {
+		t.Fatalf("Write sent %d and %d frames, want 1 each", len(sa.frames), len(sb.frames))
+	}
+	if got, want := reds(sa.last()), []byte{7, 8, 1, 2}; !bytes.Equal(got, want) {
+		t.Errorf("strip a sent %v, want %v", got, want)
+	}
+
+	s.Clear()
+	if len(sa.frames) != 2 || len(sb.frames) != 2 {
+		t.Fatalf("Clear sent %d and %d frames, want 2 each", len(sa.frames), len(sb.frames))
+	}
+	if got := reds(sa.last()); !bytes.Equal(got, make([]byte, 4)) {
+		t.Errorf("strip a sent %v after Clear", got)
+	}
+}
+
+func TestSegmentsShareStrip(t *testing.T) {
+	s, n := newStrip(neopixel.OrderRGB, 6)
+	segments := neopixel.Split(n, []string{"left", "right"}, []int{3, 3})
+	left, right := segments[0], segments[1]
+
+	left.Write([]neopixel.RGBW{{R: 1}, {R: 1}, {R: 1}})
+	right.Write([]neopixel.RGBW{{R: 2}, {R: 2}, {R: 2}})
+	if got, want := reds(s.last()), []byte{1, 1, 1, 2, 2, 2}; !bytes.Equal(got, want) {
+		t.Errorf("sent %v, want %v", got, want)
+	}
+
+	// Each keeps its own pixels when the other changes
+	left.Write([]neopixel.RGBW{{R: 3}})
+	if got, want := reds(s.last()), []byte{3, 1, 1, 2, 2, 2}; !bytes.Equal(got, want) {
+		t.Errorf("sent %v, want %v", got, want)
+	}
+	right.Clear()
+	if got, want := reds(s.last()), []byte{3, 1, 1, 0, 0, 0}; !bytes.Equal(got, want) {
+		t.Errorf("sent %v after clearing right, want %v", got, want)
+	}
+}
@@ -0,0 +1,95 @@
+package neopixel
+
+import "time"
+
+// Effect changes blend over this long unless EffectsController says otherwise
+const DefaultTransitionTime = 500 * time.Millisecond
+
+// Type Transition blends the outgoing effect's frame into the incoming one's.
+// progress runs from 0 (all from) to 255 (all to).
+type Transition interface {
+	Name() string
+	Blend(from, to []RGBW, progress uint8, out []RGBW)
+}
+
+// Type Crossfade blends every pixel from one frame to the other
+type Crossfade struct{}
+
+func (c *Crossfade) Name() string { return "Crossfade" }
+
+func (c *Crossfade) Blend(from, to []RGBW, progress uint8, out []RGBW) {
+	for i := range out {
+		out[i] = Lerp(from[i], to[i], progress)
+	}
+}
+
+// Type Wipe sweeps the incoming frame across the strip from pixel 0, or from
+// the far end when Reverse is set
+type Wipe struct {
+	Reverse bool
+}
+
+func (w *Wipe) Name() string { return "Wipe" }
+
+func (w *Wipe) Blend(from, to []RGBW, progress uint8, out []RGBW) {
+	edge := len(out) * int(progress) / 255
+	for i := range out {
+		j := i
+		if w.Reverse {
+			j = len(out) - 1 - i
+		}
+		if j < edge {
+			out[i] = to[i]
+		} else {
+			out[i] = from[i]
+		}
+	}
+}
+
+// Type FadeThroughBlack fades the outgoing frame out over the first half and
+// the incoming one in over the second
+type FadeThroughBlack struct{}
+
+func (f *FadeThroughBlack) Name() string { return "Fade Through Black" }
+
+func (f *FadeThroughBlack) Blend(from, to []RGBW, progress uint8, out []RGBW) {
+	for i := range out {
+		if progress < 128 {
+			out[i] = scale(from[i], 255-progress*2)
+		} else {
+			out[i] = scale(to[i], (progress-128)*2+1)
+		}
+	}
+}
+
+// Type track is an effect being rendered, during a transition there are two
+type track struct {
+	effect Effect
+	pixels []RGBW
+	frame  Frame
+	start  Tick
+	last   time.Duration
+}
+
+// Func begin starts the track's effect on tick, from a blank frame
+func (t *track) begin(effect Effect, tick Tick) {
+	t.effect = effect
+	t.start = tick
+	t.last = tick.Elapsed
+	fill(t.pixels, off)
+}
+
+// Func render draws the track's next frame, a track without an effect stays
+// blank
+func (t *track) render(tick Tick, palette *Palette) {
+	if t.effect == nil {
+		return
+	}
+	t.frame.Color = palette.Current()
+	t.frame.Palette = palette
+	t.frame.Number = tick.Number - t.start.Number
+	t.frame.Elapsed = tick.Elapsed - t.start.Elapsed
+	t.frame.Delta = tick.Elapsed - t.last
+	t.last = tick.Elapsed
+	t.effect.Render(t.frame, t.pixels)
+}
@@ -0,0 +1,102 @@
+package neopixel_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/davidadeleon/gophercon2022Badge/neopixel"
+)
+
+func TestBlend(t *testing.T) {
+	r, b := neopixel.RGBW{R: 0xFF}, neopixel.RGBW{B: 0xFF}
+	from, to := []neopixel.RGBW{r, r, r, r}, []neopixel.RGBW{b, b, b, b}
+	for _, tc := range []struct {
+		transition neopixel.Transition
+		progress   uint8
+		want       []neopixel.RGBW
+	}{
+		{&neopixel.Crossfade{}, 0, from},
+		{&neopixel.Crossfade{}, 127, []neopixel.RGBW{{R: 128, B: 127}, {R: 128, B: 127}, {R: 128, B: 127}, {R: 128, B: 127}}},
+		{&neopixel.Crossfade{}, 128, []neopixel.RGBW{{R: 127, B: 128}, {R: 127, B: 128}, {R: 127, B: 128}, {R: 127, B: 128}}},
+		{&neopixel.Crossfade{}, 255, to},
+		{&neopixel.Wipe{}, 0, from},
+		{&neopixel.Wipe{}, 127, []neopixel.RGBW{b, r, r, r}},
+		{&neopixel.Wipe{}, 128, []neopixel.RGBW{b, b, r, r}},
+		{&neopixel.Wipe{}, 255, to},
+		{&neopixel.Wipe{Reverse: true}, 0, from},
+		{&neopixel.Wipe{Reverse: true}, 127, []neopixel.RGBW{r, r, r, b}},
+		{&neopixel.Wipe{Reverse: true}, 128, []neopixel.RGBW{r, r, b, b}},
+		{&neopixel.Wipe{Reverse: true}, 255, to},
+		{&neopixel.FadeThroughBlack{}, 0, from},
+		{&neopixel.FadeThroughBlack{}, 127, []neopixel.RGBW{{R: 1}, {R: 1}, {R: 1}, {R: 1}}},
+		{&neopixel.FadeThroughBlack{}, 128, []neopixel.RGBW{{B: 1}, {B: 1}, {B: 1}, {B: 1}}},
+		{&neopixel.FadeThroughBlack{}, 255, to},
+	} {
+		out := make([]neopixel.RGBW, len(from))
+		tc.transition.Blend(from, to, tc.progress, out)
+		for i := range out {
+			if out[i] != tc.want[i] {
+				t.Errorf("%s %+v at %d: %v, want %v", tc.transition.Name(), tc.transition, tc.progress, out, tc.want)
+				break
+			}
+		}
+	}
+}
+
+// Func waitFor waits for the strip to send want, and returns the frames sent
+// up to it
+func waitFor(t *testing.T, s *strip, want byte) [][]byte {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		frames := s.sent()
+		if len(frames) > 0 && reds(frames[len(frames)-1])[0] == want {
+			return frames
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("strip never sent red %d, last %v", want, reds(s.last()))
+	return nil
+}
+
+func TestFadeInAfterStop(t *testing.T) {
+	s, n := newStrip(neopixel.OrderRGB, 4)
+	e := &neopixel.EffectsController{
+		Palette:        neopixel.NewPalette(neopixel.RGBW{R: 200}),
+		FPS:            100,
+		Transition:     &neopixel.Crossfade{},
+		TransitionTime: 200 * time.Millisecond,
+	}
+	e.Init(n)
+	e.Effects = []neopixel.Effect{&neopixel.Static{}}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	e.Start(ctx)
+	waitFor(t, s, 200)
+
+	e.StopEffects()
+	stopped := len(waitFor(t, s, 0))
+	e.ChangeEffect()
+	frames := waitFor(t, s, 200)[stopped:]
+
+	// Resuming climbs back up from black rather than cutting straight in
+	prev := byte(0)
+	between := 0
+	for _, f := range frames {
+		r := reds(f)[0]
+		if r < prev {
+			t.Fatalf("fade in went down from %d to %d", prev, r)
+		}
+		if r > 0 && r < 200 {
+			between++
+		}
+		prev = r
+	}
+	if first := reds(frames[0])[0]; first >= 100 || between < 5 {
+		t.Errorf("fade in started at %d with %d frames between black and full", first, between)
+	}
+	if e.Current() == nil {
+		t.Error("no current effect after resuming")
+	}
+}
@@ -0,0 +1,16 @@
+//go:build tinygo
+
+package neopixel
+
+import (
+	"machine"
+
+	"tinygo.org/x/drivers/ws2812"
+)
+
+// Func NewWS2812 sets pin up as an output and returns a Writer for the
+// WS2812/SK6812 strip on it
+func NewWS2812(pin machine.Pin) Writer {
+	pin.Configure(machine.PinConfig{Mode: machine.PinOutput})
+	return ws2812.New(pin)
+}